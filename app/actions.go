@@ -1,91 +1,338 @@
 package app
 
-import "github.com/piotr-ku/yaml-runner-go/system"
+import (
+	"context"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
 
 // The file includes the following data structures:
 //
 // Action: Provides a data format for the actions defined in the configuration
 // file.
-//   - Command: The command associated with the action. It is a required field.
+//   - Command: The command associated with the action. It is required
+// unless Commands are set.
 //   - Rules: A slice of strings representing the rules associated with
 // the action.
 //   - Shell: Shell used to execute the command.
+//   - ShellArgs: Arguments passed to the shell before "-c".
+//   - Name: Name identifying the action, e.g. in --skip-action.
+//   - Conditions: A slice of structured rules evaluated without spawning
+// a shell.
+//   - Commands: Commands executed in order instead of a single Command.
+//   - ContinueOnFailure: Whether to execute the remaining Commands after
+// a failed one.
+//   - IgnoreErrors: Whether failures are logged as warnings and do not fail
+// the run.
+//   - CombineOutput: Whether to capture stdout and stderr as a single
+// interleaved stream.
+//   - CleanEnvironment: Whether to start the command from an empty
+// environment instead of the parent one.
+//   - EnvAllow, EnvDeny: Names of the parent environment variables passed
+// or not passed to the command.
+//   - Timeout, Directory, Environment: Timeout, working directory and
+// additional environment variables of the command.
+//   - RuleTimeout, RuleEnvironment: Timeout and additional environment
+// variables of the rules.
+//   - StdoutFile, StderrFile: Files the output of the command is written to.
+//   - MaxOutputBytes: Maximum size of the output kept in memory.
+//   - Stream: Whether to log the output line by line while the command runs.
+//   - LogLevel: Log level of the command unless it fails.
+//   - Tags: Tags selecting the action, e.g. in --tags.
 
 // Action format provides a data format for the actions defined
 // in the configuration file.
 type Action struct {
-	Command string   `validate:"required"` // action command
+	// action command
+	Command string   `validate:"required_without=Commands,excluded_with=Commands"`
 	Rules   []string // action rules
 	Shell   string   // action shell
+	Name    string   // action name
+
+	// structured rules evaluated without spawning a shell
+	Conditions []Condition `validate:"dive"`
+	// commands executed in order instead of a single command
+	Commands []string `validate:"dive,required"`
+	// render the commands and rules as Go templates with the fact values
+	Template bool
+	// execute the remaining commands after a failed one
+	ContinueOnFailure bool `yaml:"continue_on_failure"`
+	// log failures as warnings and do not fail the run
+	IgnoreErrors bool `yaml:"ignore_errors"`
+
+	// arguments passed to the shell before "-c", e.g. ["-l"]
+	ShellArgs []string `yaml:"shell_args"`
+	// capture stderr together with stdout as a single stream
+	CombineOutput bool `yaml:"combine_output"`
+	// start the command from an empty environment
+	CleanEnvironment bool `yaml:"clean_environment"`
+	// parent environment variables passed to the command
+	EnvAllow []string `yaml:"env_allow"`
+	// parent environment variables not passed to the command
+	EnvDeny []string `yaml:"env_deny"`
+	// command timeout, e.g. "30s"
+	Timeout string `validate:"duration"`
+	// timeout of every rule, e.g. "1s"
+	RuleTimeout string `yaml:"rule_timeout" validate:"duration"`
+	// command working directory
+	Directory string
+	// additional environment variables of the command and the rules
+	Environment map[string]string
+	// additional environment variables of the rules only
+	RuleEnvironment map[string]string `yaml:"rule_environment"`
+	// file the standard output of the command is written to
+	StdoutFile string `yaml:"stdout_file"`
+	// file the standard error of the command is written to
+	StderrFile string `yaml:"stderr_file"`
+	// maximum size of stdout and stderr each kept in memory, unlimited if 0
+	MaxOutputBytes int `yaml:"max_output_bytes" validate:"gte=0"`
+	// log every line of the output at debug level while the command runs
+	Stream bool
+	// log level of the command unless it fails, e.g. "debug"
+	LogLevel string `yaml:"log_level" validate:"omitempty,loglevel"`
+	// tags selecting the action with --tags
+	Tags []string
+}
+
+// CommandList returns the commands of the action: the Commands, or
+// the single Command if they are not set.
+func (a Action) CommandList() []string {
+	if len(a.Commands) > 0 {
+		return a.Commands
+	}
+	return []string{a.Command}
+}
+
+// name returns the name of the action, or its commands joined with "; "
+// if the name is not set.
+func (a Action) name() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return strings.Join(a.CommandList(), "; ")
+}
+
+// ActionResult provides the result of an action in a run.
+type ActionResult struct {
+	Action   Action           // action defined in the configuration
+	Executed bool             // whether an action command was executed
+	Result   system.Command   // last executed command with its output
+	Commands []system.Command // executed commands with their outputs
+}
+
+// Failed reports whether a command of the action was executed and failed.
+// Failures of actions ignoring errors are not reported.
+func (r ActionResult) Failed() bool {
+	if !r.Executed || r.Action.IgnoreErrors {
+		return false
+	}
+	for _, c := range r.Commands {
+		if commandResult(&c) == "failure" {
+			return true
+		}
+	}
+	return false
 }
 
-// executeActions executes a list of actions based on the provided facts.
-func executeActions(actions []Action, facts Facts) {
+// executeActions executes a list of actions based on the provided facts
+// and returns their results. Execution stops when the context is done,
+// the actions not reached are not included in the results.
+func executeActions(ctx context.Context, actions []Action,
+	facts Facts) []ActionResult {
+	results := []ActionResult{}
 	for _, action := range actions {
+		// stop execution if the run was cancelled
+		if ctx.Err() != nil {
+			break
+		}
+		result := ActionResult{Action: action}
 		// check action rules
-		if checkActionRules(action, facts) {
-			c := system.NewCommand(action.Command)
-			// set facts as environment variables
-			c.Environment = facts.toEnvironment()
-			// set shell
-			if action.Shell != "" {
-				c.Shell = action.Shell
-			}
-			// execute command
-			_ = c.Execute()
-			// log
-			logActionExecuted(action, &c)
+		if checkActionRules(ctx, action, facts) {
+			result.Commands, result.Executed = executeAction(ctx, action,
+				facts)
+			result.Result = result.Commands[len(result.Commands)-1]
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// executeAction executes the commands of the action in order. Execution
+// stops at the first failed command unless ContinueOnFailure is set.
+// It returns the commands with their results and false if no command
+// was executed.
+func executeAction(ctx context.Context, action Action,
+	facts Facts) ([]system.Command, bool) {
+	commands := []system.Command{}
+	executed := false
+	for _, command := range action.CommandList() {
+		c, ok := executeActionCommand(ctx, action, command, facts)
+		commands = append(commands, c)
+		executed = executed || ok
+		if commandResult(&c) == "failure" && !action.ContinueOnFailure {
+			break
 		}
 	}
+	return commands, executed
 }
 
-// checkActionRules checks the rules of an action against the provided facts.
-// It returns true if all rules pass, otherwise false.
-func checkActionRules(action Action, facts Facts) bool {
+// executeActionCommand renders the action command with the fact values if
+// templating is enabled and executes it with the facts set as environment
+// variables, also as a JSON object in FactsJSONVariable. It returns
+// the executed command and false if the command cannot be rendered.
+func executeActionCommand(ctx context.Context, action Action,
+	template string, facts Facts) (system.Command, bool) {
+	environment := facts.toEnvironment()
+	command, err := action.render(template, environment)
+	if err != nil {
+		logRenderFailed(template, err)
+		return system.Command{Command: template, Error: err}, false
+	}
+
+	c := system.NewCommand(command)
+	// set facts as environment variables
+	c.Environment = environment
+	c.Environment[FactsJSONVariable] = facts.toJSON()
+	// set shell
+	if action.Shell != "" {
+		c.Shell = action.Shell
+	}
+	c.ShellArgs = action.ShellArgs
+	c.CombineOutput = action.CombineOutput
+	c.CleanEnvironment = action.CleanEnvironment
+	c.EnvAllow = action.EnvAllow
+	c.EnvDeny = action.EnvDeny
+	c.StdoutFile = action.StdoutFile
+	c.StderrFile = action.StderrFile
+	c.MaxOutputBytes = action.MaxOutputBytes
+	c.Stream = action.Stream
+	setCommandOptions(&c, action.Timeout, action.Directory,
+		action.Environment)
+	// execute command
+	startTime := time.Now()
+	_ = c.Execute(ctx)
+	metrics.observeActionExecuted(time.Since(startTime), commandResult(&c))
+	// log
+	logActionExecuted(&c, action)
+	return c, true
+}
+
+// checkActionRules checks the rules and conditions of an action against
+// the provided facts. It returns true if all rules pass, otherwise false.
+// A rule which cannot be rendered does not pass. The rules get the fact
+// values and the rule environment of the action as environment variables
+// and the rule timeout of the action.
+func checkActionRules(ctx context.Context, action Action,
+	facts Facts) bool {
+	environment := facts.toEnvironment()
 	for _, rule := range action.Rules {
-		c := system.NewCommand(rule)
-		c.Environment = facts.toEnvironment()
-		_ = c.Execute()
-		logRuleChecked(rule, &c)
-		if c.Rc != 0 {
+		if !evaluateRule(ctx, rule, environment, action).Passed {
+			return false
+		}
+	}
+
+	for _, condition := range action.Conditions {
+		passed := condition.check(environment)
+		logConditionChecked(condition, environment, passed)
+		if !passed {
 			return false
 		}
 	}
 	return true
 }
 
-// logRuleChecked logs the result of a rule check.
-func logRuleChecked(rule string, c *system.Command) {
-	l := system.NewLogBuilder("rule checked")
-	l.Level("debug")
-	l.Set("command", rule)
-	l.Set("dir", c.Directory)
-	l.Set("rc", c.Rc)
-	l.Set("stdout", c.Stdout)
-	l.Set("stderr", c.Stderr)
-	l.Set("error", c.Error)
-	l.Save()
-}
-
-// logActionExecuted logs the execution of an action.
-func logActionExecuted(action Action, c *system.Command) {
-	var level string
+// checkRule renders the rule with the fact values and executes it in
+// the directory. It returns true if the rule command finished with zero
+// return code.
+func checkRule(ctx context.Context, rule string,
+	environment map[string]string, directory string) bool {
+	return evaluateRule(ctx, rule, environment,
+		Action{Directory: directory}).Passed
+}
+
+// render renders the command or rule of the action with the fact values
+// if templating is enabled, otherwise it returns the command unchanged.
+func (a Action) render(command string, data map[string]string) (string,
+	error) {
+	if !a.Template {
+		return command, nil
+	}
+	return renderCommand(command, data)
+}
+
+// ruleEnvironment returns the additional environment variables of the rules
+// of the action: the action environment overridden by the rule environment.
+func (a Action) ruleEnvironment() map[string]string {
+	environment := map[string]string{}
+	maps.Copy(environment, a.Environment)
+	maps.Copy(environment, a.RuleEnvironment)
+	return environment
+}
+
+// commandResult returns "success" if the command finished with zero
+// return code, otherwise "failure".
+func commandResult(c *system.Command) string {
+	if c.Error != nil || c.Rc != 0 {
+		return "failure"
+	}
+	return "success"
+}
+
+// commandLogger returns a logger adding the parameters followed by
+// the command details to every log message. Output written to a file is
+// logged as the file path, commands killed by their timeout are logged
+// with timed_out=true.
+func commandLogger(c *system.Command, params ...interface{}) *system.Logger {
+	logger := system.With(params...).With("command", c.Command,
+		"dir", c.Directory, "rc", c.Rc).
+		With(outputLogParams("stdout", c.Stdout, c.StdoutFile)...).
+		With(outputLogParams("stderr", c.Stderr, c.StderrFile)...).
+		With("error", c.Error)
+	if c.TimedOut {
+		return logger.With("timed_out", true)
+	}
+	return logger
+}
+
+// commandLogLevel returns the log level of an executed command: error
+// if the command failed, warn if it wrote to stderr, otherwise the default
+// level.
+func commandLogLevel(c *system.Command, defaultLevel string) string {
 	switch {
 	case c.Error != nil:
-		level = "error"
+		return "error"
 	case c.Stderr != "":
-		level = "warn"
+		return "warn"
 	default:
-		level = "debug"
-	}
-
-	l := system.NewLogBuilder("action executed")
-	l.Level(level)
-	l.Set("command", action.Command)
-	l.Set("dir", c.Directory)
-	l.Set("rc", c.Rc)
-	l.Set("stdout", c.Stdout)
-	l.Set("stderr", c.Stderr)
-	l.Set("error", c.Error)
-	l.Save()
+		return defaultLevel
+	}
+}
+
+// configuredLogLevel returns the log level of an executed command set
+// in the configuration, or the level computed from the execution outcome
+// if it is not set. Failed commands are always logged as errors.
+func configuredLogLevel(c *system.Command, logLevel string,
+	defaultLevel string) string {
+	if logLevel == "" || c.Error != nil {
+		return commandLogLevel(c, defaultLevel)
+	}
+	return logLevel
+}
+
+// logRuleChecked logs the result of a rule check.
+func logRuleChecked(c *system.Command) {
+	commandLogger(c).Log("debug", "rule checked")
+}
+
+// logActionExecuted logs the execution of an action at the configured
+// log level. Failures of actions ignoring errors are logged as warnings.
+func logActionExecuted(c *system.Command, action Action) {
+	level := configuredLogLevel(c, action.LogLevel, "debug")
+	if action.IgnoreErrors && level == "error" {
+		level = "warn"
+	}
+	commandLogger(c).Log(level, "action executed")
 }
@@ -1,7 +1,10 @@
 package app
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/piotr-ku/yaml-runner-go/system"
 	"github.com/stretchr/testify/assert"
@@ -19,6 +22,7 @@ func TestExecuteActions(t *testing.T) {
 		name    string
 		actions []Action
 		facts   Facts
+		rc      []int // return codes of the executed actions
 		stdout  string
 		stderr  string
 	}{
@@ -58,6 +62,7 @@ func TestExecuteActions(t *testing.T) {
 					},
 				},
 			},
+			rc: []int{0},
 			stdout: "^time=[^ ]+ level=DEBUG msg=\"action executed\" " +
 				"command=\"echo action 1\" " +
 				"dir=[^ ]+ rc=0 stdout=\"action 1\" stderr=\"\" " +
@@ -82,6 +87,7 @@ func TestExecuteActions(t *testing.T) {
 					},
 				},
 			},
+			rc: []int{0},
 			stdout: "^time=[^ ]+ level=DEBUG msg=\"rule checked\" " +
 				"command=\"echo rule 1\" " +
 				"dir=[^ ]+ rc=0 stdout=\"rule 1\" stderr=\"\" error=<nil>\n" +
@@ -166,12 +172,28 @@ func TestExecuteActions(t *testing.T) {
 					Shell:   defaultShell,
 				},
 			},
+			rc:     []int{1},
 			stdout: empty,
 			stderr: "^time=[^ ]+ level=ERROR msg=\"action executed\" " +
 				"command=\"echo action 6; exit 1\" " +
 				"dir=[^ ]+ rc=1 stdout=\"action 6\" " +
 				"stderr=\"\" error=\"exit status 1\"\n$",
 		},
+		{
+			name: "Action killed by its timeout",
+			actions: []Action{
+				{
+					Command: "sleep 3",
+					Shell:   defaultShell,
+					Timeout: "1s",
+				},
+			},
+			rc:     []int{124},
+			stdout: empty,
+			stderr: "^time=[^ ]+ level=ERROR msg=\"action executed\" " +
+				"command=\"sleep 3\" dir=[^ ]+ rc=124 stdout=\"\" " +
+				"stderr=\"\" error=\"signal: killed\" timed_out=true\n$",
+		},
 		{
 			name: "Action returned zero code but not empty stderr",
 			actions: []Action{
@@ -180,25 +202,316 @@ func TestExecuteActions(t *testing.T) {
 					Shell:   defaultShell,
 				},
 			},
+			rc: []int{0},
 			stdout: "^time=[^ ]+ level=WARN msg=\"action executed\" " +
 				"command=\"echo action 7 1>&2\" " +
 				"dir=[^ ]+ rc=0 stdout=\"\" stderr=\"action 7\" " +
 				"error=<nil>\n$",
 			stderr: empty,
 		},
+		{
+			name: "Action with a configured log level",
+			actions: []Action{
+				{
+					Command:  "echo action 9",
+					Shell:    defaultShell,
+					LogLevel: "info",
+				},
+			},
+			rc: []int{0},
+			stdout: "^time=[^ ]+ level=INFO msg=\"action executed\" " +
+				"command=\"echo action 9\" " +
+				"dir=[^ ]+ rc=0 stdout=\"action 9\" stderr=\"\" " +
+				"error=<nil>\n$",
+			stderr: empty,
+		},
+		{
+			name: "Failed action with a configured log level",
+			actions: []Action{
+				{
+					Command:  "echo action 10; exit 1",
+					Shell:    defaultShell,
+					LogLevel: "debug",
+				},
+			},
+			rc:     []int{1},
+			stdout: empty,
+			stderr: "^time=[^ ]+ level=ERROR msg=\"action executed\" " +
+				"command=\"echo action 10; exit 1\" " +
+				"dir=[^ ]+ rc=1 stdout=\"action 10\" " +
+				"stderr=\"\" error=\"exit status 1\"\n$",
+		},
+		{
+			name: "Action and rule rendered with fact values",
+			actions: []Action{
+				{
+					Command:  "echo deploy {{ .version }}",
+					Template: true,
+					Rules: []string{
+						"test {{ .version }} = 1.2.3",
+					},
+					Shell: defaultShell,
+				},
+			},
+			facts: Facts{
+				"version": Fact{Name: "version", Result: system.Command{
+					Rc: 0, Stdout: "1.2.3",
+				}},
+			},
+			rc: []int{0},
+			stdout: "^time=[^ ]+ level=DEBUG msg=\"rule checked\" " +
+				"command=\"test 1.2.3 = 1.2.3\" " +
+				"dir=[^ ]+ rc=0 stdout=\"\" stderr=\"\" error=<nil>\n" +
+				"time=[^ ]+ level=DEBUG msg=\"action executed\" " +
+				"command=\"echo deploy 1.2.3\" " +
+				"dir=[^ ]+ rc=0 stdout=\"deploy 1.2.3\" stderr=\"\" " +
+				"error=<nil>\n$",
+			stderr: empty,
+		},
+		{
+			name: "Action rendered with an undefined fact",
+			actions: []Action{
+				{
+					Command:  "echo deploy {{ .version }}",
+					Template: true,
+					Shell:    defaultShell,
+				},
+			},
+			stdout: empty,
+			stderr: "^time=[^ ]+ level=ERROR " +
+				"msg=\"template rendering failed\" " +
+				"command=\"echo deploy {{ .version }}\" " +
+				"error=\"rendering template .*" +
+				"map has no entry for key .*version.*\n$",
+		},
+		{
+			name: "Rule with an invalid template",
+			actions: []Action{
+				{
+					Command:  "echo action 8",
+					Template: true,
+					Rules: []string{
+						"echo {{ .version",
+					},
+					Shell: defaultShell,
+				},
+			},
+			stdout: empty,
+			stderr: "^time=[^ ]+ level=ERROR " +
+				"msg=\"template rendering failed\" " +
+				"command=\"echo {{ .version\" " +
+				"error=\"parsing template .*\n$",
+		},
+		{
+			name: "Action and rule with braces not rendered",
+			actions: []Action{
+				{
+					Command: "echo '{{ .version'",
+					Rules: []string{
+						"test '{{' = '{{'",
+					},
+					Shell: defaultShell,
+				},
+			},
+			rc: []int{0},
+			stdout: "^time=[^ ]+ level=DEBUG msg=\"rule checked\" " +
+				"command=\"test '{{' = '{{'\" " +
+				"dir=[^ ]+ rc=0 stdout=\"\" stderr=\"\" error=<nil>\n" +
+				"time=[^ ]+ level=DEBUG msg=\"action executed\" " +
+				"command=\"echo '{{ .version'\" " +
+				"dir=[^ ]+ rc=0 stdout=\"{{ .version\" stderr=\"\" " +
+				"error=<nil>\n$",
+			stderr: empty,
+		},
 	}
 
 	for _, test := range tests {
 		// Set log settings and clear buffers
-		system.LogInit(system.LogConfig{
+		_ = system.LogInit(system.LogConfig{
 			File:  "testing_buffer",
 			Level: "debug",
 			Quiet: false,
 			JSON:  false,
 		})
 
-		executeActions(test.actions, test.facts)
+		results := executeActions(context.Background(), test.actions,
+			test.facts)
+		var rc []int
+		for _, result := range results {
+			if result.Executed {
+				rc = append(rc, result.Result.Rc)
+			}
+		}
+		assert.Equal(t, test.rc, rc, test.name)
 		assert.Regexp(t, test.stdout, system.GetTestingStdout())
 		assert.Regexp(t, test.stderr, system.GetTestingStderr())
 	}
 }
+
+// TestExecuteActionCommands tests actions with multiple commands.
+//
+// It verifies that the commands are executed in order, each logging its
+// result, and that execution stops at the first failed command unless
+// ContinueOnFailure is set.
+func TestExecuteActionCommands(t *testing.T) {
+	for _, test := range []struct {
+		Action   Action
+		Expected []string
+	}{
+		{Action: Action{Commands: []string{"echo one", "echo two"}},
+			Expected: []string{"one", "two"}},
+		{Action: Action{Commands: []string{"echo one", "exit 1",
+			"echo three"}},
+			Expected: []string{"one", ""}},
+		{Action: Action{Commands: []string{"echo one", "exit 1",
+			"echo three"}, ContinueOnFailure: true},
+			Expected: []string{"one", "", "three"}},
+		{Action: Action{Command: "echo single"},
+			Expected: []string{"single"}},
+	} {
+		_ = system.LogInit(system.LogConfig{
+			File:  "testing_buffer",
+			Level: "debug",
+		})
+
+		// when: We execute the action
+		results := executeActions(context.Background(),
+			[]Action{test.Action}, Facts{})
+
+		// then: We check the outputs of the executed commands
+		outputs := []string{}
+		for _, c := range results[0].Commands {
+			outputs = append(outputs, c.Stdout)
+		}
+		assert.True(t, results[0].Executed)
+		assert.Equal(t, test.Expected, outputs)
+		assert.Equal(t, results[0].Commands[len(outputs)-1],
+			results[0].Result)
+		logged := system.GetTestingStdout() + system.GetTestingStderr()
+		assert.Equal(t, len(outputs),
+			strings.Count(logged, "msg=\"action executed\""))
+	}
+}
+
+// TestCheckActionRulesEnvironment tests the environment of rule commands.
+//
+// It verifies that rules get the fact values, the action environment and
+// the rule environment, which take precedence in this order.
+func TestCheckActionRulesEnvironment(t *testing.T) {
+	facts := Facts{
+		"status": Fact{Name: "status", Result: system.Command{Stdout: "fact"}},
+		"mode":   Fact{Name: "mode", Result: system.Command{Stdout: "fact"}},
+	}
+	action := Action{
+		Command:         "true",
+		Environment:     map[string]string{"mode": "action", "limit": "5"},
+		RuleEnvironment: map[string]string{"limit": "10"},
+		Rules: []string{`[ "$status" = fact ]`, `[ "$mode" = action ]`,
+			`[ "$limit" = 10 ]`},
+	}
+
+	// when: We check the action rules
+	passed := checkActionRules(context.Background(), action, facts)
+
+	// then: We check that the rules passed and the maps were not modified
+	assert.True(t, passed)
+	assert.Equal(t, "fact", facts.toEnvironment()["mode"])
+	assert.Equal(t, "5", action.Environment["limit"])
+}
+
+// TestExecuteActionsIgnoreErrors tests actions ignoring errors.
+//
+// It verifies that failures of such actions are logged as warnings instead
+// of errors and are not reported as failed.
+func TestExecuteActionsIgnoreErrors(t *testing.T) {
+	for _, test := range []struct {
+		Action   Action
+		Expected string
+		Failed   bool
+	}{
+		{Action: Action{Command: "exit 3"}, Expected: "level=ERROR",
+			Failed: true},
+		{Action: Action{Command: "exit 3", IgnoreErrors: true},
+			Expected: "level=WARN", Failed: false},
+		{Action: Action{Command: "true", IgnoreErrors: true},
+			Expected: "level=INFO", Failed: false},
+	} {
+		_ = system.LogInit(system.LogConfig{
+			File:  "testing_buffer",
+			Level: "debug",
+		})
+		test.Action.LogLevel = "info"
+
+		// when: We execute the action
+		results := executeActions(context.Background(),
+			[]Action{test.Action}, Facts{})
+
+		// then: We check the log level and the failure status
+		logged := system.GetTestingStdout() + system.GetTestingStderr()
+		assert.Regexp(t, test.Expected+` msg="action executed"`, logged)
+		assert.Equal(t, test.Failed, results[0].Failed())
+	}
+}
+
+// TestExecuteActionsTraceLevel tests actions with the trace log level.
+//
+// It verifies that the trace level is a valid action log level and that
+// the executed action is logged at it.
+func TestExecuteActionsTraceLevel(t *testing.T) {
+	// given: An action logged at the trace level
+	_ = system.LogInit(system.LogConfig{
+		File:  "testing_buffer",
+		Level: "trace",
+	})
+	action := Action{Command: "true", LogLevel: "trace"}
+	assert.Nil(t, validateConfig(Config{Actions: []Action{action}}))
+
+	// when: We execute the action
+	executeActions(context.Background(), []Action{action}, Facts{})
+
+	// then: We check the log level
+	assert.Regexp(t, `level=TRACE msg="action executed"`,
+		system.GetTestingStdout())
+}
+
+// TestCheckActionRulesTimeout tests the rule timeout of actions.
+//
+// It verifies that a rule exceeding the rule timeout does not pass, while
+// the action command keeps its own timeout.
+func TestCheckActionRulesTimeout(t *testing.T) {
+	action := Action{Command: "sleep 1.2", Timeout: "5s", RuleTimeout: "1s",
+		Rules: []string{"sleep 3"}}
+
+	// when: We check the action rules
+	startTime := time.Now()
+	passed := checkActionRules(context.Background(), action, Facts{})
+
+	// then: We check that the rule timed out
+	assert.False(t, passed)
+	assert.Less(t, time.Since(startTime), 2*time.Second)
+
+	// when: We execute the action command
+	c, _ := executeActionCommand(context.Background(), action,
+		action.Command, Facts{})
+
+	// then: We check that the command did not time out
+	assert.False(t, c.TimedOut)
+}
+
+// TestExecuteActionsFactsJSON tests the facts passed to action commands
+// as a JSON object.
+func TestExecuteActionsFactsJSON(t *testing.T) {
+	facts := Facts{
+		"status": Fact{Name: "status",
+			Result: system.Command{Stdout: "running"}},
+		"load": Fact{Name: "load", Result: system.Command{Rc: 2}},
+	}
+
+	// when: We execute an action printing the facts
+	results := executeActions(context.Background(),
+		[]Action{{Command: "echo \"$" + FactsJSONVariable + "\""}}, facts)
+
+	// then: We check the JSON object
+	assert.JSONEq(t, `{"status": {"stdout": "running", "rc": 0},
+		"load": {"stdout": "", "rc": 2}}`, results[0].Result.Stdout)
+}
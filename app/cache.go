@@ -0,0 +1,122 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// CacheDir is the directory where the results of facts with a cache TTL
+// are saved. Fact results are not cached when empty.
+var CacheDir string
+
+// cachedResult provides a data format for a fact result saved in the cache.
+type cachedResult struct {
+	Saved  time.Time `json:"saved"`
+	Stdout string    `json:"stdout"`
+	Stderr string    `json:"stderr"`
+}
+
+// errCacheDirUnsafe is returned for a cache directory which other users
+// could use to plant fact results.
+var errCacheDirUnsafe = errors.New(
+	"cache directory not owned by the current user or writable by others")
+
+// cacheFile returns the path of the cache file of the fact. The file name
+// contains the fact name and a hash of the fact shell, command, shell
+// arguments, working directory, environment and raw and combined output
+// settings, so the cached result is not used after any of them is changed.
+func (fact *Fact) cacheFile() string {
+	directory, _ := filepath.Abs(fact.Directory)
+	environment := make([]string, 0, len(fact.Environment))
+	for _, name := range slices.Sorted(maps.Keys(fact.Environment)) {
+		environment = append(environment, name+"="+fact.Environment[name])
+	}
+	key := []string{fact.Shell, fact.Command,
+		strings.Join(fact.ShellArgs, "\x01"), directory,
+		strings.Join(environment, "\x01"), strconv.FormatBool(fact.RawOutput),
+		strconv.FormatBool(fact.CombineOutput)}
+	hash := sha256.Sum256([]byte(strings.Join(key, "\x00")))
+	name := fact.Name + "-" + hex.EncodeToString(hash[:8]) + ".json"
+	return filepath.Join(CacheDir, name)
+}
+
+// cacheEnabled returns true if the fact result should be cached.
+func (fact *Fact) cacheEnabled() bool {
+	return CacheDir != "" && fact.CacheTTL != ""
+}
+
+// loadCachedResult returns the cached result of the fact if it was saved
+// within the cache TTL.
+func (fact *Fact) loadCachedResult() (system.Command, bool) {
+	if !fact.cacheEnabled() {
+		return system.Command{}, false
+	}
+	ttl, err := parseDuration(fact.CacheTTL)
+	if err != nil {
+		return system.Command{}, false
+	}
+
+	if checkCacheDir(CacheDir) != nil {
+		return system.Command{}, false
+	}
+	content, err := os.ReadFile(fact.cacheFile())
+	if err != nil {
+		return system.Command{}, false
+	}
+	var cached cachedResult
+	if json.Unmarshal(content, &cached) != nil ||
+		time.Since(cached.Saved) > ttl {
+		return system.Command{}, false
+	}
+
+	return system.Command{
+		Command: fact.Command,
+		Shell:   fact.Shell,
+		Stdout:  cached.Stdout,
+		Stderr:  cached.Stderr,
+	}, true
+}
+
+// saveCachedResult saves the fact result in the cache. Failed results
+// are not cached. A warning is logged if the cache cannot be written.
+func (fact *Fact) saveCachedResult() {
+	if !fact.cacheEnabled() || fact.Result.Error != nil ||
+		fact.Result.Rc != 0 {
+		return
+	}
+
+	content, _ := json.Marshal(cachedResult{
+		Saved:  time.Now(),
+		Stdout: fact.Result.Stdout,
+		Stderr: fact.Result.Stderr,
+	})
+	err := os.MkdirAll(CacheDir, 0700)
+	if err == nil {
+		err = checkCacheDir(CacheDir)
+	}
+	if err == nil {
+		err = os.WriteFile(fact.cacheFile(), content, 0600)
+	}
+	if err != nil {
+		system.Log("warn", "fact cache failed", "name", fact.Name,
+			"error", err)
+	}
+}
+
+// logFactFromCache logs the details of a fact loaded from the cache.
+func (fact *Fact) logFactFromCache() {
+	system.Log("debug", "fact from cache", "name", fact.Name,
+		"command", fact.Command, "ttl", fact.CacheTTL,
+		"stdout", fact.Result.Stdout, "stderr", fact.Result.Stderr)
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGatherFactsFromCache tests gathering facts with a cache TTL.
+//
+// It gathers a fact twice and verifies that the second result is loaded
+// from the cache, and that the fact command is executed again when the
+// cached result expires or the command changes.
+func TestGatherFactsFromCache(t *testing.T) {
+	CacheDir = t.TempDir()
+	defer func() {
+		CacheDir = ""
+	}()
+	_ = system.LogInit(system.LogConfig{
+		File:  "testing_buffer",
+		Level: "debug",
+	})
+
+	// given: We define a fact printing the time in nanoseconds
+	fact := Fact{Name: "cached", Command: "date +%s%N", CacheTTL: "1h"}
+
+	// when: We gather the fact twice
+	first := gatherFacts(context.Background(), []Fact{fact})
+	second := gatherFacts(context.Background(), []Fact{fact})
+
+	// then: We check that the second result was loaded from the cache
+	assert.Equal(t, first["cached"].Result.Stdout,
+		second["cached"].Result.Stdout)
+	assert.Regexp(t, "level=DEBUG msg=\"fact from cache\" name=cached",
+		system.GetTestingStdout())
+
+	// when: We change the fact command
+	fact.Command = "date +%s%N; true"
+	changed := gatherFacts(context.Background(), []Fact{fact})
+
+	// then: We check that the command was executed again
+	assert.NotEqual(t, first["cached"].Result.Stdout,
+		changed["cached"].Result.Stdout)
+
+	// when: We expire the cached result
+	content := []byte(`{"saved":"2000-01-01T00:00:00Z","stdout":"stale"}`)
+	assert.Nil(t, os.WriteFile(fact.cacheFile(), content, 0600))
+	refreshed := gatherFacts(context.Background(), []Fact{fact})
+
+	// then: We check that the command was executed again
+	assert.NotEqual(t, "stale", refreshed["cached"].Result.Stdout)
+}
+
+// TestGatherFactsCacheDisabled tests that fact results are not cached
+// without the cache TTL or the cache directory, and that failed results
+// are not cached.
+func TestGatherFactsCacheDisabled(t *testing.T) {
+	CacheDir = t.TempDir()
+	defer func() {
+		CacheDir = ""
+	}()
+
+	// when: We gather a fact without the TTL and a failing fact
+	gatherFacts(context.Background(), []Fact{
+		{Name: "uncached", Command: "echo uncached"},
+		{Name: "failing", Command: "exit 1", CacheTTL: "1h"},
+	})
+
+	// then: We check that no results were cached
+	entries, err := os.ReadDir(CacheDir)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+
+	// then: We check that an invalid TTL disables the cache
+	fact := Fact{Name: "invalid", Command: "echo invalid", CacheTTL: "1x"}
+	_, ok := fact.loadCachedResult()
+	assert.False(t, ok)
+
+	// then: We check that the cache is disabled without the directory
+	CacheDir = ""
+	fact.CacheTTL = "1h"
+	assert.False(t, fact.cacheEnabled())
+}
+
+// TestGatherFactsCacheErrors tests gathering facts with an unusable cache.
+//
+// It verifies that an invalid cache file is ignored and a cache directory
+// which cannot be created is logged as a warning.
+func TestGatherFactsCacheErrors(t *testing.T) {
+	CacheDir = t.TempDir()
+	defer func() {
+		CacheDir = ""
+	}()
+	_ = system.LogInit(system.LogConfig{
+		File:  "testing_buffer",
+		Level: "debug",
+	})
+
+	// given: We define an invalid cache file
+	fact := Fact{Name: "broken", Command: "echo fresh", CacheTTL: "1h"}
+	assert.Nil(t, os.WriteFile(fact.cacheFile(), []byte("{"), 0600))
+
+	// when: We gather the fact
+	facts := gatherFacts(context.Background(), []Fact{fact})
+
+	// then: We check that the command was executed
+	assert.Equal(t, "fresh", facts["broken"].Result.Stdout)
+
+	// when: We use a file as the cache directory
+	CacheDir = filepath.Join(CacheDir, "file")
+	assert.Nil(t, os.WriteFile(CacheDir, []byte{}, 0600))
+	gatherFacts(context.Background(), []Fact{fact})
+
+	// then: We check that the warning was logged
+	assert.Regexp(t, "level=WARN msg=\"fact cache failed\" name=broken",
+		system.GetTestingStdout())
+}
+
+// TestCacheFileKey tests the cacheFile function.
+//
+// It verifies that the cache file changes with the working directory,
+// the environment and the raw and combined output settings of the fact.
+func TestCacheFileKey(t *testing.T) {
+	// given: We define a fact
+	fact := Fact{Name: "key", Command: "echo key", CacheTTL: "1h"}
+	files := map[string]bool{fact.cacheFile(): true}
+
+	// when: We change the settings of the fact
+	for _, change := range []func(*Fact){
+		func(f *Fact) { f.Directory = "/etc" },
+		func(f *Fact) { f.Environment = map[string]string{"KEY": "1"} },
+		func(f *Fact) { f.Environment = map[string]string{"KEY": "2"} },
+		func(f *Fact) { f.RawOutput = true },
+		func(f *Fact) { f.CombineOutput = true },
+	} {
+		change(&fact)
+		files[fact.cacheFile()] = true
+	}
+
+	// then: We check that every change gave a different cache file
+	assert.Len(t, files, 6)
+}
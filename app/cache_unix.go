@@ -0,0 +1,24 @@
+//go:build unix
+
+package app
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkCacheDir returns errCacheDirUnsafe if the cache directory is not
+// owned by the current user or is writable by the group or others.
+func checkCacheDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if info.Mode().Perm()&0o022 != 0 ||
+		(ok && int(stat.Uid) != os.Getuid()) {
+		return fmt.Errorf("%w: %s", errCacheDirUnsafe, dir)
+	}
+	return nil
+}
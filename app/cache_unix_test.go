@@ -0,0 +1,46 @@
+//go:build unix
+
+package app
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGatherFactsCacheUnsafeDir tests gathering facts with a cache
+// directory writable by others.
+//
+// It verifies that results are neither loaded from nor saved in the
+// directory and that a warning is logged.
+func TestGatherFactsCacheUnsafeDir(t *testing.T) {
+	CacheDir = t.TempDir()
+	defer func() {
+		CacheDir = ""
+	}()
+	_ = system.LogInit(system.LogConfig{
+		File:  "testing_buffer",
+		Level: "debug",
+	})
+
+	// given: A cached result in a directory writable by others
+	fact := Fact{Name: "unsafe", Command: "echo fresh", CacheTTL: "1h"}
+	content := []byte(`{"saved":"` + time.Now().Format(time.RFC3339) +
+		`","stdout":"planted"}`)
+	assert.Nil(t, os.WriteFile(fact.cacheFile(), content, 0600))
+	assert.Nil(t, os.Chmod(CacheDir, 0777))
+
+	// when: We gather the fact
+	facts := gatherFacts(context.Background(), []Fact{fact})
+
+	// then: We check that the cached result was not used
+	assert.Equal(t, "fresh", facts["unsafe"].Result.Stdout)
+	assert.Regexp(t, "level=WARN msg=\"fact cache failed\" name=unsafe "+
+		"error=\"cache directory not owned",
+		system.GetTestingStdout())
+	assert.ErrorIs(t, checkCacheDir(CacheDir), errCacheDirUnsafe)
+}
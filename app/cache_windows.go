@@ -0,0 +1,11 @@
+package app
+
+import "os"
+
+// checkCacheDir returns an error if the cache directory does not exist.
+// Windows reports no owner and no group or other permission bits, so
+// the directory is protected only by its ACL, e.g. in the user profile.
+func checkCacheDir(dir string) error {
+	_, err := os.Stat(dir)
+	return err
+}
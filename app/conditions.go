@@ -0,0 +1,143 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// The file includes the following data structures:
+//
+// Condition: Provides a data format for the structured rules defined
+// in the configuration file. Conditions are evaluated without spawning
+// a shell.
+//   - Fact: The name of the fact the condition refers to. It is a required
+// field.
+//   - Equals: The value the fact must be equal to.
+//   - Matches: The regular expression the fact must match.
+//   - GreaterThan: The number the fact must be greater than.
+//   - LessThan: The number the fact must be less than.
+
+// Condition provides a data format for the structured rules defined
+// in the configuration file.
+type Condition struct {
+	Fact        string   `validate:"required"` // fact name
+	Equals      *string  // expected value
+	Matches     string   // regular expression
+	GreaterThan *float64 `yaml:"greater_than"` // lower bound
+	LessThan    *float64 `yaml:"less_than"`    // upper bound
+}
+
+// check evaluates the condition against the provided environment.
+// It returns true if the fact exists and all operators pass.
+func (condition Condition) check(environment map[string]string) bool {
+	value, exists := environment[condition.Fact]
+	if !exists {
+		return false
+	}
+
+	if condition.Equals != nil && value != *condition.Equals {
+		return false
+	}
+
+	if condition.Matches != "" {
+		matched, err := regexp.MatchString(condition.Matches, value)
+		if err != nil || !matched {
+			return false
+		}
+	}
+
+	return condition.checkNumber(value)
+}
+
+// checkNumber evaluates the numeric operators of the condition. It returns
+// false if the value is not a number and any numeric operator is set.
+func (condition Condition) checkNumber(value string) bool {
+	if condition.GreaterThan == nil && condition.LessThan == nil {
+		return true
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+
+	if condition.GreaterThan != nil && number <= *condition.GreaterThan {
+		return false
+	}
+
+	return condition.LessThan == nil || number < *condition.LessThan
+}
+
+// validate checks that the condition defines at least one operator,
+// the regular expression compiles and the referenced fact is defined,
+// unless it may be output by the fact provider.
+func (condition Condition) validate(facts []Fact, provider bool) error {
+	if condition.Equals == nil && condition.Matches == "" &&
+		condition.GreaterThan == nil && condition.LessThan == nil {
+		return fmt.Errorf("condition for fact %q has no operator",
+			condition.Fact)
+	}
+
+	if _, err := regexp.Compile(condition.Matches); err != nil {
+		return fmt.Errorf("condition for fact %q has invalid regexp: %w",
+			condition.Fact, err)
+	}
+
+	if !provider && !factDefined(facts, condition.Fact) {
+		return fmt.Errorf("condition references undefined fact %q",
+			condition.Fact)
+	}
+
+	return nil
+}
+
+// validateConditions validates conditions of all actions defined
+// in the configuration and returns the first error encountered.
+func validateConditions(config Config) error {
+	for _, action := range config.Actions {
+		for _, condition := range action.Conditions {
+			err := condition.validate(config.Facts, config.Provider != "")
+			if err != nil {
+				return fmt.Errorf("action %q: %w",
+					strings.Join(action.CommandList(), "; "), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// factDefined checks if the name refers to a defined fact or to a value
+// exported by a fact.
+func factDefined(facts []Fact, name string) bool {
+	for _, fact := range facts {
+		if fact.envName() == name {
+			return true
+		}
+		if fact.exportsValues() && strings.HasPrefix(name, fact.envName()+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// exportsValues reports whether the fact exports values besides its own:
+// values parsed from its output, or described by its HTTP probe or stat.
+func (fact *Fact) exportsValues() bool {
+	return fact.Parse != "" || fact.HTTP != nil || fact.Stat != ""
+}
+
+// logConditionChecked logs the result of a condition check.
+func logConditionChecked(condition Condition, environment map[string]string,
+	passed bool) {
+	l := system.NewLogBuilder("condition checked")
+	l.Level("debug")
+	l.Set("fact", condition.Fact)
+	l.Set("value", environment[condition.Fact])
+	l.Set("passed", passed)
+	l.Save()
+}
@@ -0,0 +1,198 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestConditionCheck tests the check method of the Condition struct.
+//
+// It evaluates a set of conditions against a predefined environment and
+// compares the results with the expected values.
+func TestConditionCheck(t *testing.T) {
+	environment := map[string]string{
+		"load":     "16",
+		"hostname": "web-01",
+		"state":    "running",
+	}
+	running := "running"
+	stopped := "stopped"
+	ten := 10.0
+	fifteen := 15.0
+	twenty := 20.0
+
+	for _, test := range []struct {
+		name      string
+		condition Condition
+		expected  bool
+	}{
+		{"equals", Condition{Fact: "state", Equals: &running}, true},
+		{"not equals", Condition{Fact: "state", Equals: &stopped}, false},
+		{"matches", Condition{Fact: "hostname", Matches: "^web-"}, true},
+		{"not matches", Condition{Fact: "hostname", Matches: "^db-"}, false},
+		{"invalid regexp", Condition{Fact: "hostname", Matches: "("}, false},
+		{"greater than", Condition{Fact: "load", GreaterThan: &fifteen},
+			true},
+		{"not greater than", Condition{Fact: "load", GreaterThan: &twenty},
+			false},
+		{"less than", Condition{Fact: "load", LessThan: &twenty}, true},
+		{"not less than", Condition{Fact: "load", LessThan: &ten}, false},
+		{"range", Condition{Fact: "load", GreaterThan: &ten,
+			LessThan: &twenty}, true},
+		{"not a number", Condition{Fact: "state", GreaterThan: &ten}, false},
+		{"undefined fact", Condition{Fact: "missing", Matches: ".*"}, false},
+	} {
+		assert.Equal(t, test.expected, test.condition.check(environment),
+			test.name)
+	}
+}
+
+// TestValidateConditions tests the validateConditions function.
+//
+// It parses configurations with conditions and verifies that invalid
+// conditions are reported as validation errors.
+func TestValidateConditions(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "valid conditions",
+			input: `
+                facts:
+                - name: load
+                  command: echo 16
+                - name: instance
+                  command: cat instance.json
+                  parse: json
+                actions:
+                - command: echo ordinal-repaint-daisy
+                  conditions:
+                  - fact: load
+                    greater_than: 15
+                  - fact: instance_state
+                    equals: running
+            `,
+			expected: "",
+		},
+		{
+			name: "undefined fact",
+			input: `
+                facts:
+                - name: load
+                  command: echo 16
+                actions:
+                - command: echo ordinal-repaint-daisy
+                  conditions:
+                  - fact: missing
+                    equals: 0
+            `,
+			expected: "action \"echo ordinal-repaint-daisy\": " +
+				"condition references undefined fact \"missing\"",
+		},
+		{
+			name: "fact output by the provider",
+			input: `
+                provider: echo region=eu
+                actions:
+                - command: echo ordinal-repaint-daisy
+                  conditions:
+                  - fact: region
+                    equals: eu
+            `,
+			expected: "",
+		},
+		{
+			name: "missing operator",
+			input: `
+                facts:
+                - name: load
+                  command: echo 16
+                actions:
+                - command: echo ordinal-repaint-daisy
+                  conditions:
+                  - fact: load
+            `,
+			expected: "action \"echo ordinal-repaint-daisy\": " +
+				"condition for fact \"load\" has no operator",
+		},
+		{
+			name: "invalid regexp",
+			input: `
+                facts:
+                - name: load
+                  command: echo 16
+                actions:
+                - command: echo ordinal-repaint-daisy
+                  conditions:
+                  - fact: load
+                    matches: "("
+            `,
+			expected: "action \"echo ordinal-repaint-daisy\": " +
+				"condition for fact \"load\" has invalid regexp: ",
+		},
+	} {
+		config, err := parseYaml([]byte(test.input))
+		assert.Nil(t, err, test.name)
+
+		validated := validateConfig(config)
+		if test.expected == "" {
+			assert.Nil(t, validated, test.name)
+			continue
+		}
+		assert.ErrorContains(t, validated, test.expected, test.name)
+	}
+}
+
+// TestExecuteActionsWithConditions tests the execution of actions with
+// conditions.
+//
+// It executes actions with passing and failing conditions and verifies
+// that only the action with passing conditions was executed.
+func TestExecuteActionsWithConditions(t *testing.T) {
+	// Set log settings and clear buffers
+	_ = system.LogInit(system.LogConfig{
+		File:  "testing_buffer",
+		Level: "debug",
+		Quiet: false,
+		JSON:  false,
+	})
+
+	zero := "0"
+	fifteen := 15.0
+	facts := Facts{
+		"load": Fact{Name: "load", Result: system.Command{Stdout: "16"}},
+		"rc":   Fact{Name: "rc", Result: system.Command{Stdout: "0"}},
+	}
+	actions := []Action{
+		{
+			Command: "echo action 1",
+			Conditions: []Condition{
+				{Fact: "load", GreaterThan: &fifteen},
+				{Fact: "rc", Equals: &zero},
+			},
+		},
+		{
+			Command: "echo action 2",
+			Conditions: []Condition{
+				{Fact: "load", LessThan: &fifteen},
+			},
+		},
+	}
+
+	executeActions(context.Background(), actions, facts)
+
+	assert.Regexp(t, "level=DEBUG msg=\"condition checked\" fact=load "+
+		"value=16 passed=true\n.*"+
+		"level=DEBUG msg=\"condition checked\" fact=rc value=0 "+
+		"passed=true\n.*"+
+		"level=DEBUG msg=\"action executed\" command=\"echo action 1\"",
+		system.GetTestingStdout())
+	assert.Regexp(t, "level=DEBUG msg=\"condition checked\" fact=load "+
+		"value=16 passed=false\n$", system.GetTestingStdout())
+	assert.NotContains(t, system.GetTestingStdout(), "echo action 2")
+}
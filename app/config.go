@@ -2,12 +2,24 @@ package app
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"hash/adler32"
+	"io"
+	"math/rand/v2"
+	"net/http"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/robfig/cron/v3"
 	"gopkg.in/yaml.v3"
 )
 
@@ -30,48 +42,249 @@ import (
 // validating a configuration file.
 
 var (
-	mockJSONMarshal      = json.Marshal
-	mockParseYaml        = parseYaml
-	mockValidateConfig   = validateConfig
-	mockRegisterDuration = registerDuration
-	mockAdler32Hash      = adler32Hash
+	mockJSONMarshal     = json.Marshal
+	mockParseYaml       = parseYaml
+	mockValidateConfig  = validateConfig
+	mockConfigValidator = configValidator
+	mockAdler32Hash     = adler32Hash
+	mockRandomDuration  = rand.N[time.Duration]
+	mockLookPath        = exec.LookPath
 )
 
+// mockStdin is the standard input the configuration is read from.
+var mockStdin io.Reader = os.Stdin
+
+// stdinConfig caches the configuration read from the standard input,
+// which can be read only once. It is reused in the following runs
+// in daemon mode.
+var stdinConfig []byte
+
 // Daemon provides a data format for daemon settings defined
 // in the configuration file.
 type Daemon struct {
-	Interval string `validate:"duration"`
+	Interval   string `validate:"duration"`
+	RunTimeout string `yaml:"run_timeout" validate:"duration"`
+	Cron       string // cron expression scheduling the runs
+	Jitter     string `validate:"duration"` // random delay added to runs
+
+	// longest wait after repeated failed runs, backoff is disabled if empty
+	MaxBackoff string `yaml:"max_backoff" validate:"duration"`
+	// consecutive failed runs starting the backoff, 1 if zero
+	FailureThreshold int `yaml:"failure_threshold" validate:"gte=0"`
+}
+
+// minimalInterval is the shortest daemon interval allowed. It prevents
+// the daemon from spinning without a pause between runs.
+const minimalInterval = 100 * time.Millisecond
+
+// ValidateInterval checks that the daemon interval is set to a valid
+// duration not shorter than the minimal interval. It is required in daemon
+// mode only, after merging the configuration file, the environment
+// and the flags.
+func (d Daemon) ValidateInterval() error {
+	if d.Interval == "" {
+		return errors.New("daemon interval or cron is not set")
+	}
+	interval, err := parseDuration(d.Interval)
+	if err != nil {
+		return fmt.Errorf("invalid daemon interval %q: %w", d.Interval, err)
+	}
+	if interval < minimalInterval {
+		return fmt.Errorf("daemon interval %s is shorter than %s", interval,
+			minimalInterval)
+	}
+	return nil
+}
+
+// validateCron checks that the daemon cron expression is valid and
+// the interval is not set at the same time.
+func (d Daemon) validateCron() error {
+	if d.Cron == "" {
+		return nil
+	}
+	if d.Interval != "" {
+		return errors.New("daemon interval and cron are mutually exclusive")
+	}
+	if _, err := cron.ParseStandard(d.Cron); err != nil {
+		return fmt.Errorf("invalid daemon cron %q: %w", d.Cron, err)
+	}
+	return nil
+}
+
+// Wait returns how long the daemon should wait for the next run after
+// a run started at start and finished at end. If the cron expression is
+// set, it waits until the next scheduled time, otherwise until the interval
+// since the start of the run has elapsed. A random delay up to the jitter
+// is added to spread the runs of multiple daemons.
+func (d Daemon) Wait(start time.Time, end time.Time) time.Duration {
+	var jitter time.Duration
+	if maxJitter, err := parseDuration(d.Jitter); err == nil &&
+		maxJitter > 0 {
+		jitter = mockRandomDuration(maxJitter)
+	}
+
+	if schedule, err := cron.ParseStandard(d.Cron); err == nil {
+		return schedule.Next(end).Sub(end) + jitter
+	}
+	interval, _ := parseDuration(d.Interval)
+	return max(interval+jitter-end.Sub(start), 0)
+}
+
+// LogOverrun logs a warning if a run took at least as long as the daemon
+// interval, so the next run starts without a pause. Runs scheduled by
+// the cron expression are not checked.
+func (d Daemon) LogOverrun(runDuration time.Duration) {
+	interval, err := parseDuration(d.Interval)
+	if err != nil || d.Cron != "" || runDuration < interval {
+		return
+	}
+	system.Log("warn", "run overran interval", "run_ms",
+		runDuration.Milliseconds(), "interval_ms", interval.Milliseconds())
+}
+
+// Backoff returns the wait for the next run after the number of
+// consecutive failed runs. Once the failures reach the threshold, the wait,
+// or the interval if longer, is doubled with every failure up to
+// the maximal backoff. The wait is returned unchanged if the backoff is
+// disabled.
+func (d Daemon) Backoff(wait time.Duration, failures int) time.Duration {
+	maxBackoff, err := parseDuration(d.MaxBackoff)
+	threshold := max(d.FailureThreshold, 1)
+	if err != nil || maxBackoff <= 0 || failures < threshold {
+		return wait
+	}
+	interval, _ := parseDuration(d.Interval)
+	backoff := max(wait, interval)
+	for i := threshold; i <= failures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return max(min(backoff, maxBackoff), wait)
 }
 
 // Config provides a data format for the configuration file.
 type Config struct {
-	Daemon  Daemon           `validate:""`
-	Logging system.LogConfig `validate:""`
-	Facts   []Fact           `validate:"dive"`          // facts slice
-	Actions []Action         `validate:"required,dive"` // actions slice
-	Hash    uint32
+	Daemon   Daemon           `validate:""`
+	Logging  system.LogConfig `validate:""`
+	Facts    []Fact           `validate:"unique=Name,dive"` // facts slice
+	Actions  []Action         `validate:"required,dive"`    // actions slice
+	Defaults Defaults         // default command settings
+	Before   string           // command run before gathering facts
+	After    string           // command run after executing actions
+	Provider string           // command outputting additional facts
+	WorkDir  string           `yaml:"workdir"` // working directory
+	Hash     uint32
+
+	// URL receiving a POST request with the details of every failed action
+	NotifyURL string `yaml:"notify_url" validate:"omitempty,url"`
+
+	// options set explicitly, also to false or empty values
+	set setOptions
+}
+
+// setOptions is a bitmap of the options of the configuration set
+// explicitly, so that false and empty values override other values when
+// merging, e.g. to clear the log file inherited from the configuration file.
+type setOptions uint8
+
+const (
+	quietSet setOptions = 1 << iota
+	jsonSet
+	fileSet
+	levelSet
+	intervalSet
+)
+
+// SetLogFile sets the log file explicitly, so it overrides the log file
+// of the configuration it is merged into, also when empty.
+func (c *Config) SetLogFile(file string) {
+	c.Logging.File = file
+	c.set |= fileSet
+}
+
+// SetLogLevel sets the log level explicitly, so it overrides the log level
+// of the configuration it is merged into, also when empty.
+func (c *Config) SetLogLevel(level string) {
+	c.Logging.Level = level
+	c.set |= levelSet
+}
+
+// SetInterval sets the daemon interval explicitly, so it overrides
+// the interval of the configuration it is merged into, also when empty.
+func (c *Config) SetInterval(interval string) {
+	c.Daemon.Interval = interval
+	c.set |= intervalSet
+}
+
+// SetQuiet sets the quiet logging option explicitly, so it overrides
+// the option of the configuration it is merged into, also when false.
+func (c *Config) SetQuiet(quiet bool) {
+	c.Logging.Quiet = quiet
+	c.set |= quietSet
+}
+
+// SetJSON sets the JSON logging option explicitly, so it overrides
+// the option of the configuration it is merged into, also when false.
+func (c *Config) SetJSON(jsonFormat bool) {
+	c.Logging.JSON = jsonFormat
+	c.set |= jsonSet
 }
 
 // Merge merges the fields of the provided Config into the receiver Config.
 func (c *Config) Merge(m Config) {
 	// Merge Daemon fields
-	if m.Daemon.Interval != "" {
+	if m.Daemon.Interval != "" || m.set&intervalSet != 0 {
 		c.Daemon.Interval = m.Daemon.Interval
 	}
+	if m.Daemon.RunTimeout != "" {
+		c.Daemon.RunTimeout = m.Daemon.RunTimeout
+	}
+	if m.Daemon.Cron != "" {
+		c.Daemon.Cron = m.Daemon.Cron
+	}
+	if m.Daemon.Jitter != "" {
+		c.Daemon.Jitter = m.Daemon.Jitter
+	}
+	if m.Daemon.MaxBackoff != "" {
+		c.Daemon.MaxBackoff = m.Daemon.MaxBackoff
+	}
+	if m.Daemon.FailureThreshold != 0 {
+		c.Daemon.FailureThreshold = m.Daemon.FailureThreshold
+	}
 
 	// Merge Logging fields
-	if m.Logging.File != "" {
+	if m.Logging.File != "" || m.set&fileSet != 0 {
 		c.Logging.File = m.Logging.File
 	}
-	if m.Logging.Level != "" {
+	if m.Logging.Level != "" || m.set&levelSet != 0 {
 		c.Logging.Level = m.Logging.Level
 	}
-	if m.Logging.Quiet {
+	if m.Logging.Quiet || m.set&quietSet != 0 {
 		c.Logging.Quiet = m.Logging.Quiet
 	}
-	if m.Logging.JSON {
+	if m.Logging.JSON || m.set&jsonSet != 0 {
 		c.Logging.JSON = m.Logging.JSON
 	}
+	if m.Logging.Format != "" {
+		c.Logging.Format = m.Logging.Format
+	}
+	if m.Logging.Color {
+		c.Logging.Color = true
+	}
+	if m.Logging.QuietExceptErrors {
+		c.Logging.QuietExceptErrors = true
+	}
+	if m.Logging.FileMode != "" {
+		c.Logging.FileMode = m.Logging.FileMode
+	}
+	if m.Logging.DirMode != "" {
+		c.Logging.DirMode = m.Logging.DirMode
+	}
+	if m.Logging.Truncate {
+		c.Logging.Truncate = true
+	}
+	if len(m.Logging.Files) > 0 {
+		c.Logging.Files = m.Logging.Files
+	}
 
 	// Merge Facts
 	if len(m.Facts) > 0 {
@@ -82,6 +295,46 @@ func (c *Config) Merge(m Config) {
 	if len(m.Actions) > 0 {
 		c.Actions = append(c.Actions, m.Actions...)
 	}
+
+	// Merge default command settings
+	c.Defaults.merge(m.Defaults)
+
+	// Merge hooks
+	if m.Before != "" {
+		c.Before = m.Before
+	}
+	if m.After != "" {
+		c.After = m.After
+	}
+	if m.Provider != "" {
+		c.Provider = m.Provider
+	}
+	if m.NotifyURL != "" {
+		c.NotifyURL = m.NotifyURL
+	}
+
+	// Merge working directory
+	if m.WorkDir != "" {
+		c.WorkDir = m.WorkDir
+	}
+}
+
+// normalizeLevels normalizes the log levels of the logging settings,
+// facts and actions, e.g. "WARNING" to "warn", so that casing and aliases
+// do not fail the validation.
+func (c *Config) normalizeLevels() {
+	c.Logging.Level = system.NormalizeLevel(c.Logging.Level)
+	for i := range c.Logging.Files {
+		file := &c.Logging.Files[i]
+		file.Level = system.NormalizeLevel(file.Level)
+	}
+	for i := range c.Facts {
+		c.Facts[i].LogLevel = system.NormalizeLevel(c.Facts[i].LogLevel)
+	}
+	for i := range c.Actions {
+		action := &c.Actions[i]
+		action.LogLevel = system.NormalizeLevel(action.LogLevel)
+	}
 }
 
 // CalculateHash calculates a Adler-32 hash from the Config struct
@@ -110,43 +363,254 @@ func adler32Hash(data []byte) (uint32, error) {
 	return hash.Sum32(), err
 }
 
+// configFetchTimeout is the time allowed to fetch a configuration file
+// from a URL.
+const configFetchTimeout = 10 * time.Second
+
 // LoadConfigFile loads a configuration file, validates it, and returns
-// the resulting Config.
-func LoadConfigFile(file string) Config {
+// the resulting Config. The file can be a local path, an http:// or
+// https:// URL, or "-" to read the standard input. It returns an IOError,
+// ParseError or ValidationError if the file cannot be loaded.
+func LoadConfigFile(file string) (Config, error) {
+	config, err := parseConfigFile(file)
+	if err != nil {
+		return Config{}, err
+	}
+
+	// validate configuration file
+	validate := mockValidateConfig(config)
+	if validate != nil {
+		return Config{}, system.WrapError(&ValidationError{Err: validate})
+	}
+
+	return config, nil
+}
+
+// ConfigDir is the directory of configuration fragments loaded after
+// the configuration file, disabled if empty.
+var ConfigDir string
+
+// LoadConfig loads the configuration file followed by the *.yaml and *.yml
+// files of ConfigDir in lexical order, merges them into one Config and
+// validates it. The configuration file is skipped if empty. Without
+// ConfigDir it is the same as LoadConfigFile. It returns an IOError,
+// ParseError or ValidationError if the configuration cannot be loaded.
+func LoadConfig(file string) (Config, error) {
+	if ConfigDir == "" {
+		return LoadConfigFile(file)
+	}
+	files, err := configDirFiles(ConfigDir)
+	if err != nil {
+		return Config{}, system.WrapError(&IOError{Err: err})
+	}
+	if file != "" {
+		files = append([]string{file}, files...)
+	}
+
+	var config Config
+	for _, file := range files {
+		fragment, err := parseConfigFile(file)
+		if err != nil {
+			return Config{}, err
+		}
+		config.Merge(fragment)
+	}
+
+	if err := mockValidateConfig(config); err != nil {
+		return Config{}, system.WrapError(&ValidationError{Err: err})
+	}
+	return config, nil
+}
+
+// configDirFiles returns the paths of the *.yaml and *.yml files
+// of the directory sorted by name.
+func configDirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		extension := filepath.Ext(entry.Name())
+		if !entry.IsDir() && (extension == ".yaml" || extension == ".yml") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// parseConfigFile loads a configuration file without validating it.
+// It resolves the relative paths against the directory of the file and
+// applies the default command settings. It returns an IOError or
+// ParseError if the file cannot be loaded.
+func parseConfigFile(file string) (Config, error) {
 	// read configuration file
-	configContent, err := os.ReadFile(file)
-	// notest
+	configContent, err := readConfig(file)
 	if err != nil {
-		system.FatalError("IOError", err.Error())
-		return Config{}
+		return Config{}, system.WrapError(&IOError{Err: err})
 	}
 
 	// parse configuration file
 	config, err := mockParseYaml(configContent)
-	// notest
 	if err != nil {
-		system.FatalError("ParseError", err.Error())
-		return Config{}
+		return Config{}, system.WrapError(&ParseError{Err: err})
 	}
 
-	// validate configuration file
-	validate := mockValidateConfig(config)
-	// notest
-	if validate != nil {
-		system.FatalError("ValidationError", validate.Error())
-		return Config{}
+	// resolve the working directory relative to the configuration directory
+	config.WorkDir = resolvePath(configDir(file), config.WorkDir)
+
+	// apply default command settings
+	config.applyDefaults()
+
+	// normalize log levels, e.g. "WARNING" to "warn"
+	config.normalizeLevels()
+
+	// resolve output files relative to the configuration directory
+	config.resolveOutputFiles(configDir(file))
+
+	return config, nil
+}
+
+// readConfig reads the content of a configuration file from a local path
+// or the standard input, or fetches it from an http:// or https:// URL.
+func readConfig(file string) ([]byte, error) {
+	if file == "-" {
+		return readStdinConfig()
+	}
+	if isURL(file) {
+		return fetchConfig(file)
+	}
+	return os.ReadFile(file)
+}
+
+// isURL returns true if the configuration file is an http:// or https://
+// URL.
+func isURL(file string) bool {
+	return strings.HasPrefix(file, "http://") ||
+		strings.HasPrefix(file, "https://")
+}
+
+// readStdinConfig reads the content of a configuration file from
+// the standard input. The content is read once and cached.
+func readStdinConfig() ([]byte, error) {
+	if stdinConfig != nil {
+		return stdinConfig, nil
+	}
+	content, err := io.ReadAll(mockStdin)
+	if err != nil {
+		return nil, err
 	}
+	stdinConfig = content
+	return content, nil
+}
+
+// fetchConfig fetches the content of a configuration file from the URL.
+// It returns an error if the response status is not 200 OK.
+func fetchConfig(url string) ([]byte, error) {
+	client := &http.Client{Timeout: configFetchTimeout}
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", url,
+			response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
 
+// LoadConfigEnvironment returns the configuration overrides defined
+// by the YRG_* environment variables:
+//   - YRG_DAEMON_INTERVAL: daemon.interval
+//   - YRG_DAEMON_RUN_TIMEOUT: daemon.run_timeout
+//   - YRG_LOG_FILE: logging.file
+//   - YRG_LOG_LEVEL: logging.level
+//   - YRG_LOG_QUIET: logging.quiet
+//   - YRG_LOG_JSON: logging.json
+//   - YRG_LOG_FORMAT: logging.format
+//   - YRG_LOG_FILE_MODE: logging.file_mode
+//   - YRG_LOG_DIR_MODE: logging.dir_mode
+//
+// Boolean values are parsed with strconv.ParseBool. Valid values are set
+// explicitly, so false values override the configuration file, invalid
+// values are ignored.
+func LoadConfigEnvironment() Config {
+	config := Config{
+		Daemon: Daemon{
+			Interval:   os.Getenv("YRG_DAEMON_INTERVAL"),
+			RunTimeout: os.Getenv("YRG_DAEMON_RUN_TIMEOUT"),
+		},
+		Logging: system.LogConfig{
+			File:     os.Getenv("YRG_LOG_FILE"),
+			Level:    os.Getenv("YRG_LOG_LEVEL"),
+			Format:   os.Getenv("YRG_LOG_FORMAT"),
+			FileMode: os.Getenv("YRG_LOG_FILE_MODE"),
+			DirMode:  os.Getenv("YRG_LOG_DIR_MODE"),
+		},
+	}
+	quiet, err := strconv.ParseBool(os.Getenv("YRG_LOG_QUIET"))
+	if err == nil {
+		config.SetQuiet(quiet)
+	}
+	jsonFormat, err := strconv.ParseBool(os.Getenv("YRG_LOG_JSON"))
+	if err == nil {
+		config.SetJSON(jsonFormat)
+	}
 	return config
 }
 
 // parseYaml parses the provided YAML content into a Config struct
-// and returns it. If an error occurs during unmarshaling, it is
-// also returned.
+// and returns it. Options present in the content are set explicitly.
+// If an error occurs during unmarshaling, it is also returned.
 func parseYaml(content []byte) (Config, error) {
 	var structure Config
-	err := yaml.Unmarshal([]byte(content), &structure)
-	return structure, err
+	if err := yaml.Unmarshal([]byte(content), &structure); err != nil {
+		return structure, err
+	}
+
+	// detect options present in the content
+	var options explicitOptions
+	if err := yaml.Unmarshal(content, &options); err != nil {
+		return structure, err
+	}
+	options.apply(&structure)
+	return structure, nil
+}
+
+// explicitOptions provides a data format for detecting the options present
+// in the configuration file, also when set to false or empty values.
+type explicitOptions struct {
+	Daemon struct {
+		Interval *string
+	}
+	Logging struct {
+		File  *string
+		Level *string
+		Quiet *bool
+		JSON  *bool
+	}
+}
+
+// apply sets the options present in the configuration file explicitly
+// in the configuration.
+func (o explicitOptions) apply(c *Config) {
+	if o.Daemon.Interval != nil {
+		c.SetInterval(*o.Daemon.Interval)
+	}
+	if o.Logging.File != nil {
+		c.SetLogFile(*o.Logging.File)
+	}
+	if o.Logging.Level != nil {
+		c.SetLogLevel(*o.Logging.Level)
+	}
+	if o.Logging.Quiet != nil {
+		c.SetQuiet(*o.Logging.Quiet)
+	}
+	if o.Logging.JSON != nil {
+		c.SetJSON(*o.Logging.JSON)
+	}
 }
 
 // DurationValidator is a custom validator for duration strings.
@@ -163,14 +627,44 @@ func newDurationValidator() *DurationValidator {
 
 // Validate is the validation method for duration strings.
 // It checks if the duration string is valid by attempting to parse it using
-// time.ParseDuration().
+// parseDuration(), which accepts time.ParseDuration() strings, plain
+// integers as seconds and human durations, e.g. "90 minutes".
 func (*DurationValidator) Validate(fl validator.FieldLevel) bool {
 	durationStr := fl.Field().String()
 	// field is not required
 	if durationStr == "" {
 		return true
 	}
-	_, err := time.ParseDuration(durationStr)
+	_, err := parseDuration(durationStr)
+	return err == nil
+}
+
+// identifierPattern matches names which are legal shell identifiers.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateIdentifier is the validation method for environment variable
+// names. It checks if the name is a legal shell identifier.
+func validateIdentifier(fl validator.FieldLevel) bool {
+	return identifierPattern.MatchString(fl.Field().String())
+}
+
+// validateRegexp is the validation method for regular expressions
+// filtering the fact output. It checks if the expression compiles and has
+// a capture group.
+func validateRegexp(fl validator.FieldLevel) bool {
+	pattern, err := regexp.Compile(fl.Field().String())
+	return err == nil && pattern.NumSubexp() > 0
+}
+
+// validateFileMode validates the octal permission of log files.
+func validateFileMode(fl validator.FieldLevel) bool {
+	_, err := system.ParseFileMode(fl.Field().String())
+	return err == nil
+}
+
+// validateDirMode validates the octal permission of log directories.
+func validateDirMode(fl validator.FieldLevel) bool {
+	_, err := system.ParseDirMode(fl.Field().String())
 	return err == nil
 }
 
@@ -178,25 +672,93 @@ func (*DurationValidator) Validate(fl validator.FieldLevel) bool {
 // and returns any validation errors encountered.
 // If the configuration is valid, it returns nil.
 func validateConfig(config Config) error {
-	// register duration validator
-	validate, err := mockRegisterDuration()
+	return config.Validate()
+}
+
+// Validate validates the configuration using the validation tags,
+// including the custom duration rule, and checks the cron expression,
+// the fact names and the action conditions. In strict mode references
+// to undefined facts are checked as well. It returns all validation errors
+// as ValidationErrors, or nil if the configuration is valid. Errors
+// of the validation tags are translated into FieldErrors naming the fields
+// by their keys in the configuration file. It panics if the custom
+// validation rules cannot be registered.
+func (c Config) Validate() error {
+	// get the validator with the custom validation rules
+	validate, err := mockConfigValidator()
 	if err != nil {
 		panic(err)
 	}
 
-	return validate.Struct(config)
+	errs := []error{
+		validate.Struct(c),
+		c.Daemon.validateCron(),
+		validateFactNames(c.Facts),
+		validateConditions(c),
+	}
+
+	// references to undefined facts are errors only in strict mode
+	if StrictValidation {
+		errs = append(errs, validateReferences(c))
+	}
+
+	return newValidationErrors(errs...)
 }
 
-// registerDuration registers a custom validation function "duration" with
-// the validator and returns the validator instance and an error, if any.
-func registerDuration() (*validator.Validate, error) {
+// configValidator returns the validator of the configuration with
+// the custom validation rules registered and an error, if any.
+// The validator is created once and reused, since the translations
+// of the validation errors can be registered only once.
+var configValidator = sync.OnceValues(newConfigValidator)
+
+// newConfigValidator registers the custom validation functions "duration",
+// "identifier", "regexp", "filemode" and "dirmode", the "loglevel" alias
+// and the translations of the validation errors with a new validator and
+// returns the validator instance and an error, if any.
+func newConfigValidator() (*validator.Validate, error) {
 	// Create a new instance of DurationValidator.
 	v := newDurationValidator()
 
 	// Create a validator instance.
 	validate := v.validator
 
+	// Register the alias "loglevel" for the supported log levels.
+	validate.RegisterAlias("loglevel", "oneof=trace debug info warn error")
+
 	// Register the custom validation function "duration" with
 	// the validator.
-	return validate, validate.RegisterValidation("duration", v.Validate)
+	if err := validate.RegisterValidation("duration", v.Validate); err != nil {
+		return validate, err
+	}
+
+	// Register the custom validation function "identifier" with
+	// the validator.
+	err := validate.RegisterValidation("identifier", validateIdentifier)
+	if err != nil {
+		return validate, err
+	}
+
+	// Register the custom validation function "regexp" with
+	// the validator.
+	err = validate.RegisterValidation("regexp", validateRegexp)
+	if err != nil {
+		return validate, err
+	}
+
+	// Register the custom validation function "filemode" with
+	// the validator.
+	err = validate.RegisterValidation("filemode", validateFileMode)
+	if err != nil {
+		return validate, err
+	}
+
+	// Register the custom validation function "dirmode" with
+	// the validator.
+	err = validate.RegisterValidation("dirmode", validateDirMode)
+	if err != nil {
+		return validate, err
+	}
+
+	// Register the translations of the validation errors.
+	return validate, registerTranslations(validate)
 }
@@ -3,19 +3,33 @@ package app
 import (
 	"encoding/json"
 	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/piotr-ku/yaml-runner-go/system"
 	"github.com/stretchr/testify/assert"
 )
 
-const codeIOError = 64
-const codeParseError = 65
-const codeValidationError = 66
 const testingConfigFile = "../config-testing.yaml"
 
+// assertErrorName asserts that the error is a system.Error with
+// the expected name.
+func assertErrorName(t *testing.T, expected string, err error) {
+	t.Helper()
+	var appErr *system.Error
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, expected, appErr.Name)
+	}
+}
+
 // TestParseYamlWithValidData tests the parseYaml function with valid data.
 //
 // It defines the input, which is the contents of a valid YAML file.
@@ -51,6 +65,7 @@ func TestParseYamlWithValidData(t *testing.T) {
 		},
 		Actions: []Action{
 			{
+				Name:    "action1",
 				Rules:   []string{"rule1"},
 				Command: "echo rectangle-fencing-unclip",
 			},
@@ -127,7 +142,8 @@ func TestParseYamlWithMissingData(t *testing.T) {
 			{Name: "fact1", Command: ""},
 		},
 		Actions: []Action{
-			{Rules: nil, Command: "echo carnation-secrecy-twins"},
+			{Name: "action1", Rules: nil,
+				Command: "echo carnation-secrecy-twins"},
 		},
 	}
 	assert.Equal(t, expected, result)
@@ -267,7 +283,8 @@ func TestParseYamlWithValidInputAndExtraFields(t *testing.T) {
 			{Name: "fact2", Command: "echo arrange-tamale-deserving"},
 		},
 		Actions: []Action{
-			{Rules: []string{}, Command: "echo diploma-fame-equity"},
+			{Name: "action1", Rules: []string{},
+				Command: "echo diploma-fame-equity"},
 		},
 	}
 	assert.Equal(t, expectedConfig, config)
@@ -436,7 +453,8 @@ func TestLoadConfigWithoutMerging(t *testing.T) {
 	file := testingConfigFile
 
 	// when: We call the LoadConfig function with the input to get the result.
-	config := LoadConfigFile(file)
+	config, err := LoadConfigFile(file)
+	assert.Nil(t, err)
 
 	// then: We check that the function returns the expected config and
 	// no error.
@@ -446,6 +464,25 @@ func TestLoadConfigWithoutMerging(t *testing.T) {
 	assert.Equal(t, true, config.Logging.JSON)
 }
 
+// TestConfigMergeDefaults tests merging the default command settings.
+//
+// It verifies that the set defaults override the merged ones and that
+// the default environment variables are combined.
+func TestConfigMergeDefaults(t *testing.T) {
+	// given: We define a configuration with defaults
+	config := Config{Defaults: Defaults{Shell: "/bin/sh", Timeout: "5s",
+		Environment: map[string]string{"A": "1", "B": "1"}}}
+
+	// when: We merge other defaults
+	config.Merge(Config{Defaults: Defaults{Timeout: "9s", Directory: "/tmp",
+		Environment: map[string]string{"B": "2", "C": "2"}}})
+
+	// then: We check the merged defaults
+	assert.Equal(t, Defaults{Shell: "/bin/sh", Timeout: "9s",
+		Directory: "/tmp", Environment: map[string]string{"A": "1",
+			"B": "2", "C": "2"}}, config.Defaults)
+}
+
 // TestLoadConfigWithMerging is a test function that verifies the behavior
 // of the LoadConfigWithMerging function.
 //
@@ -466,6 +503,9 @@ func TestLoadConfigWithMerging(t *testing.T) {
 			Level: "warn",
 			Quiet: true,
 			JSON:  true,
+			Files: []system.LogFile{
+				{Path: "./yaml-runner-go-audit.log", Level: "info"},
+			},
 		},
 		Facts: []Fact{
 			{Name: "MergedFact", Command: "echo mergedFact"},
@@ -476,7 +516,8 @@ func TestLoadConfigWithMerging(t *testing.T) {
 	}
 
 	// when: We call the LoadConfig function with the input to get the result.
-	config := LoadConfigFile(file)
+	config, err := LoadConfigFile(file)
+	assert.Nil(t, err)
 
 	// when: We merge configuration values.
 	config.Merge(merge)
@@ -507,6 +548,10 @@ func TestLoadConfigWithMerging(t *testing.T) {
 			Expected: config.Logging.JSON,
 			Got:      merge.Logging.JSON,
 		},
+		{
+			Expected: config.Logging.Files,
+			Got:      merge.Logging.Files,
+		},
 		{
 			Expected: config.Facts[len(config.Facts)-1].Name,
 			Got:      merge.Facts[len(merge.Facts)-1].Name,
@@ -531,48 +576,28 @@ func TestLoadConfigWithMerging(t *testing.T) {
 // TestLoadConfiFileIOError is a unit test function that tests
 // the behavior of the LoadConfigFile function when an IO error occurs.
 //
-// It mocks the os.Exit function, defines an input which is
-// a non-existing config file, and checks that the function will cause
-// a fatal error. The function then checks that the expected config
-// and no error are returned.
+// It defines an input which is a non-existing config file, and checks
+// that the function returns an empty config and an IOError.
 func TestLoadConfiFileIOError(t *testing.T) {
-	// mock os.Exit
-	var rc int
-	system.MockOsExit = func(code int) {
-		rc = code
-	}
-	defer func() {
-		system.MockOsExit = os.Exit
-	}()
-
 	// given: We define the input, which is an non-existing config file
 	file := "../non-existing-file.yaml"
 
-	// then: We check that the function will cause a fatal error
-	LoadConfigFile(file)
+	// when: We call the LoadConfigFile function with the input
+	config, err := LoadConfigFile(file)
 
-	// then: We check that the function returns the expected config and
-	// no error.
-	assert.Equal(t, codeIOError, rc)
+	// then: We check that the function returns an empty config and
+	// the IOError.
+	assert.Equal(t, Config{}, config)
+	assertErrorName(t, "IOError", err)
 }
 
 // TestLoadConfiFileParseError is a test function that tests the behavior
 // of LoadConfigFile when encountering a parse error in the config file.
 //
-// The function mocks the os.Exit function, the parseYaml function,
-// and defines the input as a non-existing config file. It then checks
-// that the LoadConfigFile function causes a fatal error and returns
-// the expected config and no error.
+// The function mocks the parseYaml function and defines the input file.
+// It then checks that the LoadConfigFile function returns an empty config
+// and a ParseError.
 func TestLoadConfiFileParseError(t *testing.T) {
-	// mock os.Exit
-	var rc int
-	system.MockOsExit = func(code int) {
-		rc = code
-	}
-	defer func() {
-		system.MockOsExit = os.Exit
-	}()
-
 	// mock parseYaml
 	mockParseYaml = func(_ []byte) (Config, error) {
 		return Config{}, errors.New("fake YAML error")
@@ -584,31 +609,22 @@ func TestLoadConfiFileParseError(t *testing.T) {
 	// given: We define the input, which is an non-existing config file
 	file := testingConfigFile
 
-	// then: We check that the function will cause a fatal error
-	LoadConfigFile(file)
+	// when: We call the LoadConfigFile function with the input
+	config, err := LoadConfigFile(file)
 
-	// then: We check that the function returns the expected config and
-	// no error.
-	assert.Equal(t, codeParseError, rc)
+	// then: We check that the function returns an empty config and
+	// the ParseError.
+	assert.Equal(t, Config{}, config)
+	assertErrorName(t, "ParseError", err)
 }
 
 // TestLoadConfiFileValidationError is a test function that tests
 // the behavior of the LoadConfigFile function when a validation error occurs.
 //
-// This function mocks the os.Exit function, the validateConfig function,
-// and defines the input file. It then calls the LoadConfigFile function
-// and checks that it causes a fatal error. Finally, it asserts
-// that the return value of the function is the expected error code.
+// This function mocks the validateConfig function and defines the input
+// file. It then calls the LoadConfigFile function and checks that it
+// returns an empty config and a ValidationError.
 func TestLoadConfiFileValidationError(t *testing.T) {
-	// mock os.Exit
-	var rc int
-	system.MockOsExit = func(code int) {
-		rc = code
-	}
-	defer func() {
-		system.MockOsExit = os.Exit
-	}()
-
 	// mock validateConfig
 	mockValidateConfig = func(_ Config) error {
 		return errors.New("fake validation error")
@@ -620,12 +636,145 @@ func TestLoadConfiFileValidationError(t *testing.T) {
 	// given: We define the input, which is an non-existing config file
 	file := testingConfigFile
 
-	// then: We check that the function will cause a fatal error
-	LoadConfigFile(file)
+	// when: We call the LoadConfigFile function with the input
+	config, err := LoadConfigFile(file)
 
-	// then: We check that the function returns the expected config and
-	// no error.
-	assert.Equal(t, codeValidationError, rc)
+	// then: We check that the function returns an empty config and
+	// the ValidationError.
+	assert.Equal(t, Config{}, config)
+	assertErrorName(t, "ValidationError", err)
+}
+
+// TestLoadConfigFileFromURL tests loading the configuration file
+// from an HTTP URL.
+//
+// It serves the testing configuration file with an HTTP test server and
+// checks that it is loaded the same way as the local file. It also checks
+// that a non-200 response and an unreachable server return an IOError.
+func TestLoadConfigFileFromURL(t *testing.T) {
+	// given: We serve the testing configuration file
+	content, err := os.ReadFile(testingConfigFile)
+	assert.Nil(t, err)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/runner.yaml" {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(content)
+		}))
+
+	// when: We load the configuration file from the URL
+	config, err := LoadConfigFile(server.URL + "/runner.yaml")
+
+	// then: We check that it equals the local configuration file
+	assert.Nil(t, err)
+	expected, _ := LoadConfigFile(testingConfigFile)
+	assert.Equal(t, expected, config)
+
+	// then: We check that a non-200 response returns an IOError
+	config, err = LoadConfigFile(server.URL + "/missing.yaml")
+	assert.Equal(t, Config{}, config)
+	assertErrorName(t, "IOError", err)
+	assert.ErrorContains(t, err, "404 Not Found")
+
+	// then: We check that an unreachable server returns an IOError
+	server.Close()
+	_, err = LoadConfigFile(server.URL + "/runner.yaml")
+	assertErrorName(t, "IOError", err)
+}
+
+// TestLoadConfigFileFromStdin tests loading the configuration file
+// from the standard input.
+//
+// It mocks the standard input with the testing configuration file and
+// checks that it is loaded the same way as the local file, also when
+// loaded again after the input is consumed. It also checks that a read
+// error returns an IOError.
+func TestLoadConfigFileFromStdin(t *testing.T) {
+	defer func() {
+		mockStdin = os.Stdin
+		stdinConfig = nil
+	}()
+
+	// given: We mock the standard input with the testing configuration
+	content, err := os.ReadFile(testingConfigFile)
+	assert.Nil(t, err)
+	mockStdin = strings.NewReader(string(content))
+
+	// when: We load the configuration file twice from the standard input
+	first, err := LoadConfigFile("-")
+	assert.Nil(t, err)
+	second, err := LoadConfigFile("-")
+	assert.Nil(t, err)
+
+	// then: We check that both equal the local configuration file
+	expected, _ := LoadConfigFile(testingConfigFile)
+	assert.Equal(t, expected, first)
+	assert.Equal(t, expected, second)
+
+	// then: We check that a read error returns an IOError
+	stdinConfig = nil
+	mockStdin = iotest.ErrReader(errors.New("scalping-unmapped-fossil"))
+	_, err = LoadConfigFile("-")
+	assertErrorName(t, "IOError", err)
+}
+
+// TestLoadConfigDir tests loading the configuration file followed by
+// the configuration fragments of a directory.
+//
+// It verifies that the YAML files are merged in lexical order after
+// the configuration file, other files are ignored, and that the merged
+// configuration is validated as a whole.
+func TestLoadConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	defer func() {
+		ConfigDir = ""
+	}()
+	for name, content := range map[string]string{
+		"20-actions.yml": "actions:\n  - command: echo second\n",
+		"10-facts.yaml": "daemon:\n  interval: 3s\nfacts:\n" +
+			"  - name: fragment\n    command: echo fragment\n",
+		"notes.txt":   "not a configuration file",
+		"30-dir.yaml": "",
+	} {
+		path := filepath.Join(dir, name)
+		if name == "30-dir.yaml" {
+			assert.Nil(t, os.Mkdir(path, 0o700))
+			continue
+		}
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	ConfigDir = dir
+
+	// when: We load the fragments only
+	config, err := LoadConfig("")
+
+	// then: We check the merged configuration
+	assert.Nil(t, err)
+	assert.Equal(t, "3s", config.Daemon.Interval)
+	assert.Equal(t, "fragment", config.Facts[0].Name)
+	assert.Equal(t, "echo second", config.Actions[0].Command)
+
+	// when: We load the configuration file followed by the fragments
+	config, err = LoadConfig(testingConfigFile)
+
+	// then: We check that the fragments are merged after the file
+	assert.Nil(t, err)
+	assert.Equal(t, "3s", config.Daemon.Interval)
+	assert.Equal(t, "fragment", config.Facts[len(config.Facts)-1].Name)
+	assert.Equal(t, "echo second",
+		config.Actions[len(config.Actions)-1].Command)
+
+	// then: We check that the missing directory returns an IOError
+	ConfigDir = filepath.Join(dir, "missing")
+	_, err = LoadConfig(testingConfigFile)
+	assertErrorName(t, "IOError", err)
+
+	// then: We check that only the merged configuration must be valid
+	ConfigDir = t.TempDir()
+	_, err = LoadConfig("")
+	assertErrorName(t, "ValidationError", err)
 }
 
 // TestConfigHashing tests the hashing functionality of the Config struct.
@@ -643,7 +792,7 @@ func TestConfigHashing(t *testing.T) {
 	// when: We calculate a hash for the config file
 	config.CalculateHash()
 	got := config.Hash
-	var expected uint32 = 2915052978
+	var expected uint32 = 2605027739
 
 	// then: We check if hash was calculated as expected
 	assert.Equal(t, expected, got)
@@ -707,7 +856,10 @@ func TestDurationValidator(t *testing.T) {
 		{Duration: Data{Duration: "1m"}, Expected: true},
 		{Duration: Data{Duration: "2s"}, Expected: true},
 		{Duration: Data{Duration: "1m30s"}, Expected: true},
+		{Duration: Data{Duration: "5"}, Expected: true},
+		{Duration: Data{Duration: "1 hour, 30 mins"}, Expected: true},
 		{Duration: Data{Duration: "incorrect_format"}, Expected: false},
+		{Duration: Data{Duration: "5 fortnights"}, Expected: false},
 	} {
 		// then: We check validation results
 		// Create a new instance of DurationValidator.
@@ -729,14 +881,14 @@ func TestDurationValidator(t *testing.T) {
 // TestDurationValidatorRegisterError tests the duration validator
 // registration error.
 //
-// It mocks the registerDuration function to return a fake validator error.
+// It mocks the configValidator function to return a fake validator error.
 // It creates a new instance of DurationValidator and a validator instance.
 // It registers the custom validation function "duration" with the validator.
 // It defines the input as an empty Config.
 // It checks that the function will cause a fatal error using assert.Panics.
 func TestDurationValidatorRegisterError(t *testing.T) {
-	// mock registerDuration
-	mockRegisterDuration = func() (*validator.Validate, error) {
+	// mock configValidator
+	mockConfigValidator = func() (*validator.Validate, error) {
 		// Create a new instance of DurationValidator.
 		v := newDurationValidator()
 
@@ -748,7 +900,7 @@ func TestDurationValidatorRegisterError(t *testing.T) {
 		return validate, errors.New("fake validator error")
 	}
 	defer func() {
-		mockRegisterDuration = registerDuration
+		mockConfigValidator = configValidator
 	}()
 
 	// given: We define the input, which is an empty Config
@@ -757,3 +909,542 @@ func TestDurationValidatorRegisterError(t *testing.T) {
 	// then: We check that the function will cause a fatal error
 	assert.Panics(t, func() { _ = validateConfig(config) })
 }
+
+// TestValidateConfigWithInvalidFactParse tests the validateConfig function
+// when a fact uses an unsupported output format.
+func TestValidateConfigWithInvalidFactParse(t *testing.T) {
+	// given: We define the input, which is the contents of a invalid YAML file.
+	input := []byte(`
+        facts:
+        - name: fact1
+          command: echo cradle-unlisted-posh
+          parse: xml
+        actions:
+        - command: echo ranging-cable-wreath
+    `)
+
+	// when: We call the parseYaml function with the input to get the result.
+	config, err := parseYaml(input)
+	assert.Nil(t, err)
+	validated := validateConfig(config)
+
+	// then: We check that the function returned an error.
+	assert.NotNil(t, validated)
+}
+
+// TestValidateConfigWithFactEnvName tests the validateConfig function
+// with facts exporting their values under environment variable names.
+//
+// It verifies that only legal shell identifiers are accepted.
+func TestValidateConfigWithFactEnvName(t *testing.T) {
+	for _, test := range []struct {
+		EnvName string
+		Valid   bool
+	}{
+		{EnvName: "APACHE_STATUS", Valid: true},
+		{EnvName: "_status2", Valid: true},
+		{EnvName: "2STATUS", Valid: false},
+		{EnvName: "APACHE-STATUS", Valid: false},
+		{EnvName: "APACHE STATUS", Valid: false},
+	} {
+		// given: We define a configuration with the environment name
+		config := Config{
+			Facts: []Fact{{Name: "Apache Running Status",
+				Command: "echo running", EnvName: test.EnvName}},
+			Actions: []Action{{Command: "echo ${APACHE_STATUS}"}},
+		}
+
+		// then: We check the validation result
+		assert.Equal(t, test.Valid, validateConfig(config) == nil,
+			test.EnvName)
+	}
+}
+
+// TestValidateConfigWithInvalidFactName tests the validateConfig function
+// with fact names which are not valid environment variable names.
+//
+// It verifies that such names are rejected unless env_name is set.
+func TestValidateConfigWithInvalidFactName(t *testing.T) {
+	for _, test := range []struct {
+		Name    string
+		EnvName string
+		Valid   bool
+	}{
+		{Name: "apache_status", Valid: true},
+		{Name: "apache status", Valid: false},
+		{Name: "apache-status", Valid: false},
+		{Name: "apache-status", EnvName: "APACHE_STATUS", Valid: true},
+	} {
+		// given: We define a configuration with the fact name
+		config := Config{
+			Facts: []Fact{{Name: test.Name, Command: "echo running",
+				EnvName: test.EnvName}},
+			Actions: []Action{{Command: "echo action"}},
+		}
+
+		// when: We validate the configuration
+		err := validateConfig(config)
+
+		// then: We check the validation result
+		if test.Valid {
+			assert.Nil(t, err, test.Name)
+		} else {
+			assert.ErrorContains(t, err,
+				"is not a valid environment variable name", test.Name)
+		}
+	}
+}
+
+// TestValidateConfigWithLogLevel tests the validateConfig function with
+// log levels of facts and actions.
+func TestValidateConfigWithLogLevel(t *testing.T) {
+	for _, test := range []struct {
+		LogLevel string
+		Valid    bool
+	}{
+		{LogLevel: "", Valid: true},
+		{LogLevel: "debug", Valid: true},
+		{LogLevel: "error", Valid: true},
+		{LogLevel: "verbose", Valid: false},
+	} {
+		// given: We define a fact and an action with the log level
+		fact := Config{
+			Facts: []Fact{{Name: "fact", Command: "echo fact",
+				LogLevel: test.LogLevel}},
+			Actions: []Action{{Command: "echo action"}},
+		}
+		action := Config{
+			Actions: []Action{{Command: "echo action",
+				LogLevel: test.LogLevel}},
+		}
+
+		// then: We check the validation results
+		assert.Equal(t, test.Valid, validateConfig(fact) == nil,
+			test.LogLevel)
+		assert.Equal(t, test.Valid, validateConfig(action) == nil,
+			test.LogLevel)
+	}
+}
+
+// TestConfigValidate tests the Validate method of a configuration built
+// programmatically.
+//
+// It verifies that a valid configuration passes and that the errors
+// of an invalid configuration are aggregated.
+func TestConfigValidate(t *testing.T) {
+	// given: We define a valid configuration
+	config := Config{
+		Daemon:  Daemon{Interval: "5s"},
+		Facts:   []Fact{{Name: "fact", Command: "echo fact"}},
+		Actions: []Action{{Command: "echo action"}},
+	}
+
+	// then: We check that it is valid
+	assert.Nil(t, config.Validate())
+
+	// when: We break the duration, the cron and the fact name
+	config.Daemon.RunTimeout = "soon"
+	config.Daemon.Cron = "0 * * * *"
+	config.Facts[0].Name = "invalid-name"
+	err := config.Validate()
+
+	// then: We check that all errors are returned
+	assert.ErrorContains(t, err, "run_timeout must be a valid duration")
+	assert.ErrorContains(t, err, "interval and cron are mutually exclusive")
+	assert.ErrorContains(t, err, "is not a valid environment variable name")
+}
+
+// TestValidateConfigWithLogFile tests the validateConfig function with
+// log file paths and levels.
+//
+// It verifies that directories and unknown levels are rejected, and that
+// the special testing_buffer value is accepted.
+func TestValidateConfigWithLogFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, test := range []struct {
+		Logging  system.LogConfig
+		Expected string
+	}{
+		{Logging: system.LogConfig{File: filepath.Join(dir, "runner.log")}},
+		{Logging: system.LogConfig{File: "testing_buffer"}},
+		{Logging: system.LogConfig{File: dir},
+			Expected: "logging: file must be a valid file path"},
+		{Logging: system.LogConfig{File: dir + "/"},
+			Expected: "logging: file must be a valid file path"},
+		{Logging: system.LogConfig{Files: []system.LogFile{{Path: dir}}},
+			Expected: "logging.files[0]: path must be a valid file path"},
+		{Logging: system.LogConfig{Level: "verbose"},
+			Expected: "logging: level must be one of " +
+				"[trace debug info warn error]"},
+		{Logging: system.LogConfig{FileMode: "0660"}},
+		{Logging: system.LogConfig{FileMode: "0999"},
+			Expected: "logging: file_mode must be an octal permission " +
+				"with owner read and write, e.g. 0640"},
+		{Logging: system.LogConfig{FileMode: "0440"},
+			Expected: "logging: file_mode must be an octal permission " +
+				"with owner read and write, e.g. 0640"},
+		{Logging: system.LogConfig{DirMode: "0750"}},
+		{Logging: system.LogConfig{Format: "console"}},
+		{Logging: system.LogConfig{Format: "xml"},
+			Expected: "logging: format must be one of " +
+				"[text json logfmt console]"},
+		{Logging: system.LogConfig{DirMode: "0640"},
+			Expected: "logging: dir_mode must be an octal permission " +
+				"with full owner access, e.g. 0750"},
+	} {
+		// given: We define a configuration with the logging settings
+		config := Config{
+			Logging: test.Logging,
+			Actions: []Action{{Command: "echo action"}},
+		}
+
+		// when: We validate the configuration
+		err := validateConfig(config)
+
+		// then: We check the validation result
+		if test.Expected == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, test.Expected)
+		}
+	}
+}
+
+// TestConfigNormalizeLevels tests the normalizeLevels method.
+//
+// It verifies that uppercase and alias level names are normalized, so that
+// they pass the validation.
+func TestConfigNormalizeLevels(t *testing.T) {
+	// given: We define a configuration with uppercase and alias levels
+	config := Config{
+		Logging: system.LogConfig{
+			Level: "DEBUG",
+			Files: []system.LogFile{{Path: "runner.log", Level: "Warning"}},
+		},
+		Facts:   []Fact{{Name: "fact", Command: "echo", LogLevel: "ERR"}},
+		Actions: []Action{{Command: "echo action", LogLevel: "warning"}},
+	}
+
+	// when: We normalize the levels and validate the configuration
+	config.normalizeLevels()
+	err := validateConfig(config)
+
+	// then: We check the normalized levels and the validation result
+	assert.Nil(t, err)
+	assert.Equal(t, "debug", config.Logging.Level)
+	assert.Equal(t, "warn", config.Logging.Files[0].Level)
+	assert.Equal(t, "error", config.Facts[0].LogLevel)
+	assert.Equal(t, "warn", config.Actions[0].LogLevel)
+}
+
+// TestValidateConfigWithDuplicatedFactName tests the validateConfig function
+// when two facts share the same name.
+func TestValidateConfigWithDuplicatedFactName(t *testing.T) {
+	// given: We define the input, which is the contents of a invalid YAML file.
+	input := []byte(`
+        facts:
+        - name: fact1
+          command: echo pushcart-rejoin-sandy
+        - name: fact1
+          command: echo overdrawn-epic-mousiness
+        actions:
+        - command: echo motion-stinky-unsettled
+    `)
+
+	// when: We call the parseYaml function with the input to get the result.
+	config, err := parseYaml(input)
+	assert.Nil(t, err)
+	validated := validateConfig(config)
+
+	// then: We check that the function returned an error.
+	assert.EqualError(t, validated, "facts must contain unique values")
+}
+
+// TestDaemonValidateInterval tests the ValidateInterval method of the Daemon
+// struct with valid, empty, zero and too short intervals.
+func TestDaemonValidateInterval(t *testing.T) {
+	for _, test := range []struct {
+		Interval string
+		Expected string
+	}{
+		{Interval: "2s", Expected: ""},
+		{Interval: "100ms", Expected: ""},
+		{Interval: "", Expected: "daemon interval or cron is not set"},
+		{Interval: "2", Expected: ""},
+		{Interval: "90 minutes", Expected: ""},
+		{Interval: "two", Expected: "invalid daemon interval \"two\": " +
+			"invalid duration \"two\""},
+		{Interval: "0s", Expected: "daemon interval 0s is shorter than 100ms"},
+		{Interval: "10ms", Expected: "daemon interval 10ms is shorter " +
+			"than 100ms"},
+	} {
+		err := Daemon{Interval: test.Interval}.ValidateInterval()
+		if test.Expected == "" {
+			assert.Nil(t, err, test.Interval)
+			continue
+		}
+		assert.EqualError(t, err, test.Expected, test.Interval)
+	}
+}
+
+// TestValidateConfigWithDaemonCron tests the validateConfig function
+// with valid and invalid daemon cron expressions.
+func TestValidateConfigWithDaemonCron(t *testing.T) {
+	for _, test := range []struct {
+		Daemon   Daemon
+		Expected string
+	}{
+		{Daemon: Daemon{Cron: "0 * * * *"}, Expected: ""},
+		{Daemon: Daemon{Cron: "@hourly"}, Expected: ""},
+		{Daemon: Daemon{Cron: "0 * * *"}, Expected: "invalid daemon cron " +
+			"\"0 * * *\": expected exactly 5 fields, found 4: [0 * * *]"},
+		{Daemon: Daemon{Cron: "@hourly", Interval: "5s"},
+			Expected: "daemon interval and cron are mutually exclusive"},
+	} {
+		config := Config{
+			Daemon:  test.Daemon,
+			Actions: []Action{{Command: "echo action"}},
+		}
+		err := validateConfig(config)
+		if test.Expected == "" {
+			assert.Nil(t, err, test.Daemon.Cron)
+			continue
+		}
+		assert.EqualError(t, err, test.Expected, test.Daemon.Cron)
+	}
+}
+
+// TestDaemonWait tests the Wait method of the Daemon struct with interval
+// and cron scheduling, with and without jitter.
+func TestDaemonWait(t *testing.T) {
+	// mock random jitter as a half of the maximal jitter
+	mockRandomDuration = func(n time.Duration) time.Duration {
+		return n / 2
+	}
+	defer func() {
+		mockRandomDuration = rand.N[time.Duration]
+	}()
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	for _, test := range []struct {
+		Daemon   Daemon
+		End      time.Time
+		Expected time.Duration
+	}{
+		{Daemon: Daemon{Interval: "5s"}, End: start.Add(2 * time.Second),
+			Expected: 3 * time.Second},
+		{Daemon: Daemon{Interval: "5s"}, End: start.Add(6 * time.Second),
+			Expected: 0},
+		{Daemon: Daemon{Interval: "5s", Cron: "0 * * * *"},
+			End:      start.Add(2 * time.Second),
+			Expected: 55*time.Minute + 53*time.Second},
+		{Daemon: Daemon{Interval: "5s", Jitter: "10s"},
+			End:      start.Add(6 * time.Second),
+			Expected: 4 * time.Second},
+		{Daemon: Daemon{Cron: "0 * * * *", Jitter: "10s"},
+			End:      start.Add(2 * time.Second),
+			Expected: 55*time.Minute + 58*time.Second},
+	} {
+		assert.Equal(t, test.Expected, test.Daemon.Wait(start, test.End))
+	}
+}
+
+// TestDaemonBackoff tests the Backoff method of the Daemon struct.
+//
+// It verifies that the wait is doubled with every failure from
+// the threshold up to the maximal backoff, and that it is unchanged below
+// the threshold or without the maximal backoff.
+func TestDaemonBackoff(t *testing.T) {
+	for _, test := range []struct {
+		Daemon   Daemon
+		Failures int
+		Expected time.Duration
+	}{
+		{Daemon: Daemon{}, Failures: 5, Expected: 2 * time.Second},
+		{Daemon: Daemon{MaxBackoff: "1m"}, Failures: 0,
+			Expected: 2 * time.Second},
+		{Daemon: Daemon{MaxBackoff: "1m"}, Failures: 1,
+			Expected: 4 * time.Second},
+		{Daemon: Daemon{MaxBackoff: "1m"}, Failures: 3,
+			Expected: 16 * time.Second},
+		{Daemon: Daemon{MaxBackoff: "1m"}, Failures: 10,
+			Expected: time.Minute},
+		{Daemon: Daemon{MaxBackoff: "1m", FailureThreshold: 3}, Failures: 2,
+			Expected: 2 * time.Second},
+		{Daemon: Daemon{MaxBackoff: "1m", FailureThreshold: 3}, Failures: 4,
+			Expected: 8 * time.Second},
+		{Daemon: Daemon{MaxBackoff: "1s"}, Failures: 1,
+			Expected: 2 * time.Second},
+		{Daemon: Daemon{Interval: "5s", MaxBackoff: "1m"}, Failures: 1,
+			Expected: 10 * time.Second},
+	} {
+		assert.Equal(t, test.Expected,
+			test.Daemon.Backoff(2*time.Second, test.Failures), test)
+	}
+}
+
+// TestDaemonLogOverrun tests the LogOverrun method of the Daemon struct.
+//
+// It verifies that a warning is logged only for runs taking at least
+// as long as the interval, and never for runs scheduled by cron.
+func TestDaemonLogOverrun(t *testing.T) {
+	for _, test := range []struct {
+		Daemon      Daemon
+		RunDuration time.Duration
+		Expected    string
+	}{
+		{Daemon: Daemon{Interval: "5s"}, RunDuration: 4 * time.Second},
+		{Daemon: Daemon{Interval: "5s"}, RunDuration: 5 * time.Second,
+			Expected: "level=WARN msg=\"run overran interval\" " +
+				"run_ms=5000 interval_ms=5000\n"},
+		{Daemon: Daemon{Cron: "* * * * *"}, RunDuration: time.Hour},
+	} {
+		_ = system.LogInit(system.LogConfig{
+			File:  "testing_buffer",
+			Level: "debug",
+		})
+
+		// when: We check the run duration
+		test.Daemon.LogOverrun(test.RunDuration)
+
+		// then: We check the warning
+		if test.Expected == "" {
+			assert.Empty(t, system.GetTestingStdout())
+		} else {
+			assert.Regexp(t, test.Expected+"$", system.GetTestingStdout())
+		}
+	}
+}
+
+// TestLoadConfigEnvironment tests the LoadConfigEnvironment function.
+//
+// It sets the YRG_* environment variables and verifies that they are
+// mapped to the expected configuration fields.
+func TestLoadConfigEnvironment(t *testing.T) {
+	// given: We define the environment variables
+	t.Setenv("YRG_DAEMON_INTERVAL", "10s")
+	t.Setenv("YRG_DAEMON_RUN_TIMEOUT", "1m")
+	t.Setenv("YRG_LOG_FILE", "/tmp/yrg.log")
+	t.Setenv("YRG_LOG_LEVEL", "debug")
+	t.Setenv("YRG_LOG_QUIET", "true")
+	t.Setenv("YRG_LOG_JSON", "invalid")
+	t.Setenv("YRG_LOG_FILE_MODE", "0640")
+	t.Setenv("YRG_LOG_FORMAT", "console")
+	t.Setenv("YRG_LOG_DIR_MODE", "0750")
+
+	// when: We load the configuration from the environment
+	config := LoadConfigEnvironment()
+
+	// then: We check that the configuration matches the environment
+	expected := Config{
+		Daemon: Daemon{Interval: "10s", RunTimeout: "1m"},
+		Logging: system.LogConfig{
+			File:     "/tmp/yrg.log",
+			Level:    "debug",
+			JSON:     false,
+			Format:   "console",
+			FileMode: "0640",
+			DirMode:  "0750",
+		},
+	}
+	expected.SetQuiet(true)
+	assert.Equal(t, expected, config)
+}
+
+// TestConfigMergeClearedOptions tests merging options set explicitly
+// to empty values.
+//
+// It verifies that empty values set in the configuration file or with
+// the setters clear the inherited values, while options not set keep them.
+func TestConfigMergeClearedOptions(t *testing.T) {
+	base := Config{
+		Daemon:  Daemon{Interval: "5s"},
+		Logging: system.LogConfig{File: "./base.log", Level: "warn"},
+	}
+
+	// when: We merge a configuration without the options set
+	config := base
+	config.Merge(Config{})
+
+	// then: We check that the options are kept
+	assert.Equal(t, base, config)
+
+	// when: We merge a configuration file clearing the options
+	file, err := parseYaml([]byte(`
+        daemon:
+          interval: ""
+        logging:
+          file: ""
+          level: ""
+    `))
+	assert.Nil(t, err)
+	config = base
+	config.Merge(file)
+
+	// then: We check that the options are cleared
+	assert.Equal(t, Config{}, config)
+
+	// when: We merge arguments clearing the log file only
+	args := Config{}
+	args.SetLogFile("")
+	config = base
+	config.Merge(args)
+
+	// then: We check that only the log file is cleared
+	assert.Equal(t, "", config.Logging.File)
+	assert.Equal(t, "warn", config.Logging.Level)
+	assert.Equal(t, "5s", config.Daemon.Interval)
+}
+
+// TestConfigMergeFalseOptions tests merging boolean options set
+// explicitly to false.
+//
+// It verifies that options set to false in the configuration file,
+// the environment variables or with the setters override true values, while
+// options not set keep them.
+func TestConfigMergeFalseOptions(t *testing.T) {
+	base := Config{Logging: system.LogConfig{Quiet: true, JSON: true}}
+
+	// when: We merge a configuration without the options set
+	config := base
+	config.Merge(Config{})
+
+	// then: We check that the options are kept
+	assert.True(t, config.Logging.Quiet)
+	assert.True(t, config.Logging.JSON)
+
+	// when: We merge a configuration file setting the options to false
+	file, err := parseYaml([]byte(`
+        logging:
+          quiet: false
+          json: false
+    `))
+	assert.Nil(t, err)
+	config = base
+	config.Merge(file)
+
+	// then: We check that the options are overridden
+	assert.False(t, config.Logging.Quiet)
+	assert.False(t, config.Logging.JSON)
+
+	// when: We merge the environment variables setting the options to false
+	t.Setenv("YRG_LOG_QUIET", "false")
+	t.Setenv("YRG_LOG_JSON", "0")
+	config = base
+	config.Merge(LoadConfigEnvironment())
+
+	// then: We check that the options are overridden
+	assert.False(t, config.Logging.Quiet)
+	assert.False(t, config.Logging.JSON)
+
+	// when: We merge arguments setting the options to false
+	args := Config{}
+	args.SetQuiet(false)
+	args.SetJSON(false)
+	config = base
+	config.Merge(args)
+
+	// then: We check that the options are overridden
+	assert.False(t, config.Logging.Quiet)
+	assert.False(t, config.Logging.JSON)
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"maps"
+	"math"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// Defaults provides a data format for the default command settings
+// inherited by every fact and action of the configuration file.
+type Defaults struct {
+	Shell       string            // default shell
+	Timeout     string            `validate:"duration"` // default timeout
+	Directory   string            // default working directory
+	Environment map[string]string // default environment variables
+}
+
+// applyDefaults sets the default command settings of the configuration to
+// the facts and actions leaving them empty. The working directory of
+// the configuration is used if the defaults do not set a directory. Default
+// environment variables are added unless the fact or action defines
+// a variable with the same name.
+func (c *Config) applyDefaults() {
+	directory := defaultValue(c.Defaults.Directory, c.WorkDir)
+	for i := range c.Facts {
+		fact := &c.Facts[i]
+		fact.Shell = defaultValue(fact.Shell, c.Defaults.Shell)
+		fact.Timeout = defaultValue(fact.Timeout, c.Defaults.Timeout)
+		fact.Directory = defaultValue(fact.Directory, directory)
+		fact.Environment = defaultEnvironment(fact.Environment,
+			c.Defaults.Environment)
+	}
+	for i := range c.Actions {
+		action := &c.Actions[i]
+		action.Shell = defaultValue(action.Shell, c.Defaults.Shell)
+		action.Timeout = defaultValue(action.Timeout, c.Defaults.Timeout)
+		action.Directory = defaultValue(action.Directory, directory)
+		action.Environment = defaultEnvironment(action.Environment,
+			c.Defaults.Environment)
+	}
+}
+
+// merge merges the default command settings set in m into the defaults.
+// The environment variables of m override the variables with the same
+// name.
+func (d *Defaults) merge(m Defaults) {
+	d.Shell = defaultValue(m.Shell, d.Shell)
+	d.Timeout = defaultValue(m.Timeout, d.Timeout)
+	d.Directory = defaultValue(m.Directory, d.Directory)
+	d.Environment = defaultEnvironment(m.Environment, d.Environment)
+}
+
+// defaultValue returns the value, or the default value if it is empty.
+func defaultValue(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// defaultEnvironment returns the environment extended with the default
+// variables not defined in it.
+func defaultEnvironment(environment map[string]string,
+	defaults map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return environment
+	}
+	merged := maps.Clone(defaults)
+	maps.Copy(merged, environment)
+	return merged
+}
+
+// setCommandOptions sets the timeout, working directory and environment
+// variables defined in the configuration file to the command. Empty
+// options keep the command defaults. The timeout is rounded up to whole
+// seconds.
+func setCommandOptions(c *system.Command, timeout string, directory string,
+	environment map[string]string) {
+	if duration, err := parseDuration(timeout); err == nil &&
+		duration > 0 {
+		c.Timeout = int(math.Ceil(duration.Seconds()))
+	}
+	if directory != "" {
+		c.Directory = directory
+	}
+	if len(environment) > 0 && c.Environment == nil {
+		c.Environment = map[string]string{}
+	}
+	maps.Copy(c.Environment, environment)
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLoadConfigFileDefaults tests applying the defaults block
+// of the configuration file.
+//
+// It loads a configuration file with defaults and verifies that facts
+// and actions inherit the settings they leave empty, and that YAML anchors
+// and aliases can be used to share other settings.
+func TestLoadConfigFileDefaults(t *testing.T) {
+	// given: We define a configuration file with defaults and anchors
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	content := []byte(`
+        defaults:
+          shell: /bin/bash
+          timeout: 30s
+          directory: /tmp
+          environment:
+            REGION: eu-west-1
+            STAGE: prod
+        x-rules: &rules
+          rules:
+            - "true"
+        facts:
+          - name: inherited
+            command: echo inherited
+          - name: overridden
+            command: echo overridden
+            shell: /bin/sh
+            timeout: 1s
+            directory: /
+            environment:
+              STAGE: dev
+        actions:
+          - command: echo action
+            <<: *rules
+    `)
+	assert.Nil(t, os.WriteFile(file, content, 0600))
+
+	// when: We load the configuration file
+	config, err := LoadConfigFile(file)
+
+	// then: We check the inherited and overridden settings
+	assert.Nil(t, err)
+	inherited := config.Facts[0]
+	assert.Equal(t, "/bin/bash", inherited.Shell)
+	assert.Equal(t, "30s", inherited.Timeout)
+	assert.Equal(t, "/tmp", inherited.Directory)
+	assert.Equal(t, map[string]string{"REGION": "eu-west-1", "STAGE": "prod"},
+		inherited.Environment)
+	overridden := config.Facts[1]
+	assert.Equal(t, "/bin/sh", overridden.Shell)
+	assert.Equal(t, "1s", overridden.Timeout)
+	assert.Equal(t, "/", overridden.Directory)
+	assert.Equal(t, map[string]string{"REGION": "eu-west-1", "STAGE": "dev"},
+		overridden.Environment)
+	action := config.Actions[0]
+	assert.Equal(t, "/bin/bash", action.Shell)
+	assert.Equal(t, "30s", action.Timeout)
+	assert.Equal(t, []string{"true"}, action.Rules)
+}
+
+// TestGatherFactsCommandOptions tests the gatherFacts function with facts
+// defining the command timeout, working directory and environment.
+func TestGatherFactsCommandOptions(t *testing.T) {
+	dir := t.TempDir()
+
+	// when: We gather facts with command options
+	facts := gatherFacts(context.Background(), []Fact{
+		{Name: "options", Command: "echo $(pwd):${STAGE}", Directory: dir,
+			Environment: map[string]string{"STAGE": "dev"}},
+		{Name: "timeout", Command: "sleep 3", Timeout: "500ms"},
+	})
+
+	// then: We check the fact values
+	assert.Equal(t, dir+":dev", facts["options"].Result.Stdout)
+	assert.Equal(t, 1, facts["timeout"].Result.Timeout)
+	assert.NotNil(t, facts["timeout"].Result.Error)
+}
+
+// TestLoadConfigFileWorkDir tests the working directory of the whole
+// configuration.
+//
+// It verifies that the working directory is resolved relative to
+// the configuration file and used by facts, actions and hooks, unless they
+// set their own directory.
+func TestLoadConfigFileWorkDir(t *testing.T) {
+	// given: We define a configuration file with a working directory
+	dir := t.TempDir()
+	workDir := filepath.Join(dir, "work")
+	assert.Nil(t, os.Mkdir(workDir, 0700))
+	file := filepath.Join(dir, "config.yaml")
+	content := []byte(`
+        workdir: work
+        before: pwd > hook.log
+        facts:
+          - name: inherited
+            command: pwd
+          - name: overridden
+            command: pwd
+            directory: /
+        actions:
+          - command: pwd
+    `)
+	assert.Nil(t, os.WriteFile(file, content, 0600))
+
+	// when: We load and execute the configuration file
+	config, err := LoadConfigFile(file)
+	assert.Nil(t, err)
+	results, err := Execute(context.Background(), config)
+
+	// then: We check the working directories
+	assert.Nil(t, err)
+	assert.Equal(t, workDir, config.WorkDir)
+	assert.Equal(t, workDir, results.Facts["inherited"].Result.Stdout)
+	assert.Equal(t, "/", results.Facts["overridden"].Result.Stdout)
+	assert.Equal(t, workDir, results.Actions[0].Result.Stdout)
+	output, _ := os.ReadFile(filepath.Join(workDir, "hook.log"))
+	assert.Equal(t, workDir+"\n", string(output))
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationUnits maps the units of human durations to their lengths.
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond, "msec": time.Millisecond,
+	"millisecond": time.Millisecond, "milliseconds": time.Millisecond,
+	"s": time.Second, "sec": time.Second, "secs": time.Second,
+	"second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute,
+	"minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour,
+	"hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+}
+
+// durationPartPattern matches a number followed by a unit of human
+// durations, e.g. "90 minutes" or "1.5h".
+var durationPartPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*([a-z]+)`)
+
+// parseDuration parses a duration string accepted by time.ParseDuration,
+// e.g. "1h30m", a plain integer as seconds, e.g. "5", or a human duration
+// of numbers followed by units, optionally separated by commas or "and",
+// e.g. "90 minutes" or "1 hour and 30 mins".
+func parseDuration(value string) (time.Duration, error) {
+	if duration, err := time.ParseDuration(value); err == nil {
+		return duration, nil
+	}
+	text := strings.ToLower(strings.TrimSpace(value))
+	if seconds, err := strconv.Atoi(text); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	duration, ok := parseHumanDuration(text)
+	if !ok {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return duration, nil
+}
+
+// parseHumanDuration parses a human duration, e.g. "1 hour, 30 minutes".
+// It returns false if the text contains anything but numbers with known
+// units and their separators.
+func parseHumanDuration(text string) (time.Duration, bool) {
+	parts := durationPartPattern.FindAllStringSubmatch(text, -1)
+	separators := durationPartPattern.ReplaceAllString(text, " ")
+	for _, separator := range strings.Fields(separators) {
+		if separator != "," && separator != "and" {
+			return 0, false
+		}
+	}
+
+	var duration time.Duration
+	for _, part := range parts {
+		number, _ := strconv.ParseFloat(part[1], 64)
+		unit, ok := durationUnits[part[2]]
+		if !ok {
+			return 0, false
+		}
+		duration += time.Duration(number * float64(unit))
+	}
+	return duration, len(parts) > 0
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseDuration tests parsing strict, plain integer and human
+// durations.
+func TestParseDuration(t *testing.T) {
+	for _, test := range []struct {
+		Value    string
+		Expected time.Duration
+		Invalid  bool
+	}{
+		{Value: "1h30m", Expected: 90 * time.Minute},
+		{Value: "250ms", Expected: 250 * time.Millisecond},
+		{Value: "5", Expected: 5 * time.Second},
+		{Value: " 0 ", Expected: 0},
+		{Value: "90 minutes", Expected: 90 * time.Minute},
+		{Value: "1 hour and 30 mins", Expected: 90 * time.Minute},
+		{Value: "1 Hour, 30 Seconds", Expected: time.Hour + 30*time.Second},
+		{Value: "1.5h 10sec", Expected: 90*time.Minute + 10*time.Second},
+		{Value: "2 days", Expected: 48 * time.Hour},
+		{Value: "", Invalid: true},
+		{Value: "minutes", Invalid: true},
+		{Value: "5 fortnights", Invalid: true},
+		{Value: "1 hour or 30 minutes", Invalid: true},
+		{Value: "5s!", Invalid: true},
+	} {
+		// when: We parse the duration
+		duration, err := parseDuration(test.Value)
+
+		// then: We check the parsed duration
+		if test.Invalid {
+			assert.EqualError(t, err, "invalid duration \""+test.Value+"\"")
+			continue
+		}
+		assert.Nil(t, err, test.Value)
+		assert.Equal(t, test.Expected, duration, test.Value)
+	}
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// LoadEnvFile sets the variables defined in the environment file, e.g.
+// .env, in the process environment, so they are visible to facts
+// and actions. It returns an IOError if the file cannot be read
+// and a ParseError if it contains an invalid line.
+func LoadEnvFile(file string) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return system.WrapError(&IOError{Err: err})
+	}
+	env, err := parseEnvFile(string(content))
+	if err != nil {
+		return system.WrapError(&ParseError{Err: fmt.Errorf("%s: %w", file, err)})
+	}
+	for key, value := range env {
+		if err := os.Setenv(key, value); err != nil {
+			return system.WrapError(&OSError{Err: err})
+		}
+	}
+	return nil
+}
+
+// parseEnvFile parses the KEY=VALUE lines of an environment file. Empty
+// lines and comments starting with # are skipped, and the optional export
+// prefix is removed.
+func parseEnvFile(content string) (map[string]string, error) {
+	env := map[string]string{}
+	for i, line := range strings.Split(content, "\n") {
+		if err := parseEnvLine(env, line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	return env, nil
+}
+
+// parseEnvLine parses a line of an environment file and saves
+// the variable it defines to the environment.
+func parseEnvLine(env map[string]string, line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	key, value, found := strings.Cut(strings.TrimPrefix(line, "export "),
+		"=")
+	key = strings.TrimSpace(key)
+	if !found || !identifierPattern.MatchString(key) {
+		return fmt.Errorf("invalid variable definition %q", line)
+	}
+	parsed, err := parseEnvValue(value)
+	if err != nil {
+		return err
+	}
+	env[key] = parsed
+	return nil
+}
+
+// parseEnvValue parses a value of an environment file. Double quoted
+// values are unescaped, single quoted values are taken literally
+// and comments following the values are removed.
+func parseEnvValue(value string) (string, error) {
+	quoted, err := quotedPrefix(strings.TrimSpace(value))
+	if err != nil {
+		return "", err
+	}
+	if quoted == "" {
+		value, _, _ = strings.Cut(value, " #")
+		return strings.TrimSpace(value), nil
+	}
+
+	rest := strings.TrimSpace(strings.TrimSpace(value)[len(quoted):])
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %q after quoted value", rest)
+	}
+	if quoted[0] == '\'' {
+		return quoted[1 : len(quoted)-1], nil
+	}
+	return strconv.Unquote(quoted)
+}
+
+// quotedPrefix returns the quoted string the value starts with, or
+// an empty string if the value is not quoted.
+func quotedPrefix(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		quoted, err := strconv.QuotedPrefix(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted value %s", value)
+		}
+		return quoted, nil
+	case strings.HasPrefix(value, "'"):
+		end := strings.Index(value[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted value %s", value)
+		}
+		return value[:end+2], nil
+	}
+	return "", nil
+}
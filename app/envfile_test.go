@@ -0,0 +1,91 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseEnvFile tests parsing an environment file with comments,
+// quoted values and the export prefix.
+func TestParseEnvFile(t *testing.T) {
+	// given: We define the content of an environment file
+	content := `
+# database settings
+DB_HOST=localhost
+DB_PORT = 5432 # default port
+export DB_USER=admin
+DB_PASSWORD="se#cret \"quoted\"\n" # comment
+DB_NAME='literal\n'
+DB_EMPTY=
+	`
+
+	// when: We parse the content
+	env, err := parseEnvFile(content)
+
+	// then: We check the variables
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"DB_HOST":     "localhost",
+		"DB_PORT":     "5432",
+		"DB_USER":     "admin",
+		"DB_PASSWORD": "se#cret \"quoted\"\n",
+		"DB_NAME":     `literal\n`,
+		"DB_EMPTY":    "",
+	}, env)
+}
+
+// TestParseEnvFileErrors tests parsing invalid lines of an environment
+// file.
+//
+// It verifies that the error contains the number of the invalid line.
+func TestParseEnvFileErrors(t *testing.T) {
+	for _, test := range []struct {
+		Content  string
+		Expected string
+	}{
+		{Content: "KEY=value\nINVALID", Expected: "line 2: invalid variable"},
+		{Content: "INVALID-KEY=value", Expected: "line 1: invalid variable"},
+		{Content: `KEY="unterminated`, Expected: "invalid quoted value"},
+		{Content: "KEY='unterminated", Expected: "unterminated quoted value"},
+		{Content: `KEY="value" rest`, Expected: "unexpected \"rest\""},
+	} {
+		_, err := parseEnvFile(test.Content)
+		assert.ErrorContains(t, err, test.Expected)
+	}
+}
+
+// TestLoadEnvFile tests loading an environment file into the process
+// environment.
+//
+// It verifies that the variables are set, that a missing file returns
+// an IOError and that an invalid file returns a ParseError.
+func TestLoadEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, ".env")
+	assert.Nil(t, os.WriteFile(file, []byte("YRG_TEST_SECRET=marble\n"),
+		0600))
+	t.Setenv("YRG_TEST_SECRET", "")
+
+	// when: We load the environment file
+	err := LoadEnvFile(file)
+
+	// then: We check the process environment
+	assert.Nil(t, err)
+	assert.Equal(t, "marble", os.Getenv("YRG_TEST_SECRET"))
+
+	// when: We load a missing environment file
+	err = LoadEnvFile(filepath.Join(dir, "missing.env"))
+
+	// then: We check the IOError
+	assertErrorName(t, "IOError", err)
+
+	// when: We load an invalid environment file
+	assert.Nil(t, os.WriteFile(file, []byte("invalid\n"), 0600))
+	err = LoadEnvFile(file)
+
+	// then: We check the ParseError
+	assertErrorName(t, "ParseError", err)
+}
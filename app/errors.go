@@ -0,0 +1,66 @@
+package app
+
+// IOError is returned if a file, a directory or a URL cannot be read,
+// e.g. the configuration file. Errors returned by the application wrap
+// it in a system.Error named "IOError", which sets the exit status code.
+type IOError struct {
+	Err error // underlying error
+}
+
+// Error returns the message of the underlying error.
+func (e *IOError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *IOError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError is returned if the content of a file cannot be parsed,
+// e.g. invalid YAML of the configuration file.
+type ParseError struct {
+	Err error // underlying error
+}
+
+// Error returns the message of the underlying error.
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned if the configuration or the flags are
+// invalid, e.g. a required field is missing or a shell does not exist.
+type ValidationError struct {
+	Err error // underlying error
+}
+
+// Error returns the message of the underlying error.
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// OSError is returned if the operating system fails the run, e.g.
+// the before hook fails or an environment variable cannot be set.
+type OSError struct {
+	Err error // underlying error
+}
+
+// Error returns the message of the underlying error.
+func (e *OSError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *OSError) Unwrap() error {
+	return e.Err
+}
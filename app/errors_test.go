@@ -0,0 +1,63 @@
+package app
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestErrorTypes tests the structured error types returned by
+// LoadConfigFile.
+//
+// It verifies that callers can match the error types with errors.As,
+// reach the underlying cause, and that the exit status codes are derived
+// from the types.
+func TestErrorTypes(t *testing.T) {
+	dir := t.TempDir()
+	invalidYaml := filepath.Join(dir, "invalid.yaml")
+	assert.Nil(t, os.WriteFile(invalidYaml, []byte("facts: ["), 0o600))
+	invalidConfig := filepath.Join(dir, "config.yaml")
+	assert.Nil(t, os.WriteFile(invalidConfig, []byte("facts: []"), 0o600))
+
+	// when: We load a missing configuration file
+	_, err := LoadConfigFile(filepath.Join(dir, "missing.yaml"))
+
+	// then: We check the IOError and its cause
+	var ioError *IOError
+	assert.ErrorAs(t, err, &ioError)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assertExitCode(t, system.CodeIOError, err)
+
+	// when: We load a file with invalid YAML
+	_, err = LoadConfigFile(invalidYaml)
+
+	// then: We check the ParseError
+	var parseError *ParseError
+	assert.ErrorAs(t, err, &parseError)
+	assertExitCode(t, system.CodeParseError, err)
+
+	// when: We load an invalid configuration
+	_, err = LoadConfigFile(invalidConfig)
+
+	// then: We check the ValidationError and its field errors
+	var validationError *ValidationError
+	assert.ErrorAs(t, err, &validationError)
+	var fieldError FieldError
+	assert.ErrorAs(t, validationError, &fieldError)
+	assert.Equal(t, "actions", fieldError.Field)
+	assertExitCode(t, system.CodeValidationError, err)
+}
+
+// assertExitCode asserts that the error is a system.Error mapped
+// to the exit status code.
+func assertExitCode(t *testing.T, expected system.ErrorCode, err error) {
+	var appErr *system.Error
+	if assert.True(t, errors.As(err, &appErr)) {
+		assert.Equal(t, int(expected), system.ExitCode(appErr.Name))
+	}
+}
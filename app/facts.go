@@ -1,82 +1,401 @@
 package app
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/piotr-ku/yaml-runner-go/system"
 )
 
 // Fact provides a data format for the facts defined
 // in the configuration file.
 type Fact struct {
-	Name    string         `validate:"required"` // fact name
-	Command string         `validate:"required"` // fact command
-	Shell   string         // fact shell
-	Result  system.Command // fact result
+	Name   string         `validate:"required"` // fact name
+	Shell  string         // fact shell
+	Result system.Command // fact result
+
+	// fact command
+	Command string `validate:"required_without_all=File HTTP Stat"`
+	// file the fact value is read from instead of running a command
+	File string `validate:"omitempty,excluded_with=Command HTTP Stat,filepath"`
+	// HTTP request gathering the fact instead of a command
+	HTTP *HTTPProbe `validate:"omitempty,excluded_with=Command File Stat"`
+	// path checked for existence, type, mode, size and modification time
+	Stat string `validate:"omitempty,excluded_with=Command File HTTP,filepath"`
+
+	// arguments passed to the shell before "-c", e.g. ["-l"]
+	ShellArgs []string `yaml:"shell_args"`
+	// capture stderr together with stdout as a single stream
+	CombineOutput bool `yaml:"combine_output"`
+	// keep leading and trailing newlines of the output
+	RawOutput bool `yaml:"raw_output"`
+	// type of the fact value, "bool" for "true" if the command succeeds
+	// and "false" otherwise instead of its output
+	Type string `validate:"omitempty,oneof=bool"`
+	// regular expression whose first capture group becomes the fact value
+	Regex string `validate:"omitempty,regexp"`
+	// output format used to parse the fact values, e.g. "json"
+	Parse string `validate:"omitempty,oneof=json"`
+	// values parsed from the fact output
+	Values map[string]string
+	// how long the fact result is cached, e.g. "10m"
+	CacheTTL string `yaml:"cache_ttl" validate:"duration"`
+	// start the command from an empty environment
+	CleanEnvironment bool `yaml:"clean_environment"`
+	// parent environment variables passed to the command
+	EnvAllow []string `yaml:"env_allow"`
+	// parent environment variables not passed to the command
+	EnvDeny []string `yaml:"env_deny"`
+	// command timeout, e.g. "30s"
+	Timeout string `validate:"duration"`
+	// command working directory
+	Directory string
+	// additional environment variables of the command
+	Environment map[string]string
+	// file the standard output of the command is written to
+	StdoutFile string `yaml:"stdout_file"`
+	// file the standard error of the command is written to
+	StderrFile string `yaml:"stderr_file"`
+	// maximum size of stdout and stderr each kept in memory, unlimited if 0
+	MaxOutputBytes int `yaml:"max_output_bytes" validate:"gte=0"`
+	// log every line of the output at debug level while the command runs
+	Stream bool
+	// log level of the command unless it fails, e.g. "debug"
+	LogLevel string `yaml:"log_level" validate:"omitempty,loglevel"`
+	// rule which must pass to gather the fact
+	When string
+	// environment variable name of the fact, the fact name by default
+	EnvName string `yaml:"env_name" validate:"omitempty,identifier"`
+	// tags selecting the fact with --tags
+	Tags []string
 }
 
-// LogFactGathered logs the details of a fact that has been gathered.
+// LogFactGathered logs the details of a fact that has been gathered
+// at the configured log level.
 func (fact *Fact) logFactGathered(c system.Command) {
-	// determine log level based on command execution result
-	var level string
-	switch {
-	case c.Error != nil:
-		level = "error"
-	case c.Stderr != "":
-		level = "warn"
-	default:
-		level = "debug"
+	params := []interface{}{"name", fact.Name}
+	if fact.File != "" {
+		params = append(params, "file", fact.File)
 	}
-
-	// build and save log entry
-	l := system.NewLogBuilder("fact gathered")
-	l.Level(level)
-	l.Set("name", fact.Name)
-	l.Set("command", fact.Command)
-	l.Set("dir", c.Directory)
-	l.Set("rc", c.Rc)
-	l.Set("stdout", c.Stdout)
-	l.Set("stderr", c.Stderr)
-	l.Set("error", c.Error)
-	l.Save()
+	if fact.Stat != "" {
+		params = append(params, "stat", fact.Stat)
+	}
+	commandLogger(&c, params...).Log(
+		configuredLogLevel(&c, fact.LogLevel, "debug"), "fact gathered")
 }
 
 // Facts represents a map of fact names to their corresponding values.
 type Facts map[string]Fact
 
+// toEnvironment returns the fact values as environment variables named
+// after the environment names of the facts.
 func (facts Facts) toEnvironment() map[string]string {
 	environment := make(map[string]string)
 
-	for key, fact := range facts {
-		if fact.Result.Stdout != "" && fact.Result.Rc == 0 {
-			environment[key] = fact.Result.Stdout
+	for _, fact := range facts {
+		key := fact.envName()
+		if value, ok := fact.value(); ok {
+			environment[key] = value
+		}
+		for path, value := range fact.Values {
+			environment[key+"_"+path] = value
 		}
 	}
 
 	return environment
 }
 
+// value returns the value of the fact and false if the fact has no value:
+// the output of the successful command, or for boolean facts "true" if
+// the command succeeded and "false" otherwise.
+func (fact *Fact) value() (string, bool) {
+	if fact.Type == "bool" {
+		succeeded := fact.Result.Rc == 0 && fact.Result.Error == nil
+		return strconv.FormatBool(succeeded), true
+	}
+	return fact.Result.Stdout, fact.Result.Stdout != "" && fact.Result.Rc == 0
+}
+
+// FactsJSONVariable is the environment variable of action commands
+// containing all gathered facts as a JSON object.
+const FactsJSONVariable = "YRG_FACTS_JSON"
+
+// factJSON provides the format of a fact in FactsJSONVariable.
+type factJSON struct {
+	Stdout string `json:"stdout"`
+	Rc     int    `json:"rc"`
+}
+
+// toJSON returns the facts as a JSON object mapping the fact names to
+// their output and return code.
+func (facts Facts) toJSON() string {
+	object := make(map[string]factJSON, len(facts))
+	for name, fact := range facts {
+		object[name] = factJSON{Stdout: fact.Result.Stdout, Rc: fact.Result.Rc}
+	}
+	data, _ := json.Marshal(object)
+	return string(data)
+}
+
+// envName returns the environment variable name of the fact: EnvName
+// if set, otherwise the fact name.
+func (fact *Fact) envName() string {
+	if fact.EnvName != "" {
+		return fact.EnvName
+	}
+	return fact.Name
+}
+
+// validateFactNames returns an error if the name of a fact without
+// env_name is not a legal shell identifier, e.g. if it contains a space.
+// The env_name values are validated by their validation tag.
+func validateFactNames(facts []Fact) error {
+	for _, fact := range facts {
+		if fact.EnvName == "" && !identifierPattern.MatchString(fact.Name) {
+			return fmt.Errorf("fact %q: name is not a valid environment "+
+				"variable name, use env_name to set one", fact.Name)
+		}
+	}
+	return nil
+}
+
 // gatherFacts collects facts by executing commands and saves the results
-// in a temporary storage.
-func gatherFacts(facts []Fact) Facts {
+// in a temporary storage. Gathering stops when the context is done.
+func gatherFacts(ctx context.Context, facts []Fact) Facts {
 	// temporary storage
 	gatheredFacts := Facts{}
 
 	for _, fact := range facts {
-		// create command
-		c := system.NewCommand(fact.Command)
-		// set shell
-		if fact.Shell != "" {
-			c.Shell = fact.Shell
+		// stop gathering if the run was cancelled
+		if ctx.Err() != nil {
+			break
+		}
+		// skip facts not applicable to the environment
+		if !fact.applies(ctx, gatheredFacts) {
+			continue
 		}
-		// execute command
-		_ = c.Execute()
-		// log
-		fact.logFactGathered(c)
-		// add result
-		fact.Result = c
-
 		// save fact value to the temporary storage
-		gatheredFacts[fact.Name] = fact
+		gatheredFacts[fact.Name] = gatherFact(ctx, fact)
 	}
+	// add overridden facts not defined in the configuration
+	addOverriddenFacts(gatheredFacts)
 
 	return gatheredFacts
 }
+
+// applies checks the fact rule against the facts gathered so far.
+// It returns true if the fact has no rule, the rule passes, or the fact
+// value is overridden.
+func (fact *Fact) applies(ctx context.Context, gatheredFacts Facts) bool {
+	if _, overridden := FactOverrides[fact.Name]; overridden ||
+		fact.When == "" {
+		return true
+	}
+	if checkRule(ctx, fact.When, gatheredFacts.toEnvironment(),
+		fact.Directory) {
+		return true
+	}
+	system.Log("debug", "fact skipped", "name", fact.Name, "when", fact.When)
+	return false
+}
+
+// gatherFact executes the fact command, or reads the fact file, and returns
+// the fact with its result. The overridden value or the cached result is
+// used instead if available.
+func gatherFact(ctx context.Context, fact Fact) Fact {
+	// use overridden value
+	if overridden, ok := overrideFact(fact); ok {
+		return overridden
+	}
+	// read the fact file, check the path or probe the URL
+	if fact.gatherWithoutCommand(ctx) {
+		return fact
+	}
+	// use cached result
+	if result, ok := fact.loadCachedResult(); ok {
+		fact.Result = result
+		fact.logFactFromCache()
+		fact.processOutput()
+		return fact
+	}
+
+	// create command
+	c := system.NewCommand(fact.Command)
+	// set shell
+	if fact.Shell != "" {
+		c.Shell = fact.Shell
+	}
+	c.ShellArgs = fact.ShellArgs
+	c.CombineOutput = fact.CombineOutput
+	c.TrimOutput = !fact.RawOutput
+	c.CleanEnvironment = fact.CleanEnvironment
+	c.EnvAllow = fact.EnvAllow
+	c.EnvDeny = fact.EnvDeny
+	c.StdoutFile = fact.StdoutFile
+	c.StderrFile = fact.StderrFile
+	c.MaxOutputBytes = fact.MaxOutputBytes
+	c.Stream = fact.Stream
+	setCommandOptions(&c, fact.Timeout, fact.Directory, fact.Environment)
+	// execute command
+	startTime := time.Now()
+	_ = c.Execute(ctx)
+	metrics.observeFactGathered(time.Since(startTime))
+	// log
+	fact.logFactGathered(c)
+	// add result
+	fact.Result = c
+	fact.saveCachedResult()
+	// parse output
+	fact.processOutput()
+
+	return fact
+}
+
+// gatherWithoutCommand reads the fact file, checks the fact path
+// or performs the HTTP probe of the fact and saves the result. It returns
+// false if the fact is gathered by a command.
+func (fact *Fact) gatherWithoutCommand(ctx context.Context) bool {
+	switch {
+	case fact.File != "":
+		fact.Result = fact.readFile()
+		fact.processOutput()
+	case fact.Stat != "":
+		fact.Result, fact.Values = statPath(fact.Stat)
+	case fact.HTTP != nil:
+		startTime := time.Now()
+		result, body := fact.HTTP.probe(ctx)
+		metrics.observeFactGathered(time.Since(startTime))
+		fact.Result = result
+		fact.Values = fact.HTTP.values(result, fact.trimOutput(body))
+	default:
+		return false
+	}
+	fact.logFactGathered(fact.Result)
+	return true
+}
+
+// trimOutput trims leading and trailing newlines of the output unless
+// RawOutput is set.
+func (fact *Fact) trimOutput(output string) string {
+	if fact.RawOutput {
+		return output
+	}
+	return strings.Trim(output, "\n")
+}
+
+// readFile reads the fact value from the fact file without spawning
+// a process. The content is trimmed like command output unless RawOutput
+// is set. Read errors are saved as the result error.
+func (fact *Fact) readFile() system.Command {
+	startTime := time.Now()
+	content, err := os.ReadFile(fact.File)
+	metrics.observeFactGathered(time.Since(startTime))
+	if err != nil {
+		return system.Command{Rc: -1, Error: err}
+	}
+	return system.Command{Stdout: fact.trimOutput(string(content))}
+}
+
+// processOutput filters the fact output with the regular expression
+// and parses the filtered output.
+func (fact *Fact) processOutput() {
+	fact.filterOutput()
+	fact.parseOutput()
+}
+
+// filterOutput replaces the fact output with the first capture group
+// of the regular expression. If the output does not match, a warning is
+// logged and the fact value is empty.
+func (fact *Fact) filterOutput() {
+	if fact.Regex == "" || fact.Result.Rc != 0 {
+		return
+	}
+	pattern, err := regexp.Compile(fact.Regex)
+	if err != nil {
+		system.Log("warn", "fact regex invalid", "name", fact.Name,
+			"regex", fact.Regex, "error", err)
+		return
+	}
+	match := pattern.FindStringSubmatch(fact.Result.Stdout)
+	if len(match) < 2 {
+		system.Log("warn", "fact regex not matched", "name", fact.Name,
+			"regex", fact.Regex, "stdout", fact.Result.Stdout)
+		fact.Result.Stdout = ""
+		return
+	}
+	fact.Result.Stdout = match[1]
+}
+
+// parseOutput parses the fact output according to the fact format and saves
+// the flattened values. If the output cannot be parsed, a warning is logged
+// and the raw output is used as the fact value.
+func (fact *Fact) parseOutput() {
+	if fact.Parse != "json" || fact.Result.Rc != 0 {
+		return
+	}
+
+	data, err := decodeJSON(fact.Result.Stdout)
+	if err != nil {
+		system.Log("warn", "fact parsing failed", "name", fact.Name,
+			"parse", fact.Parse, "error", err)
+		return
+	}
+
+	fact.Values = map[string]string{}
+	flattenJSON(fact.Values, "", data)
+}
+
+// decodeJSON decodes a single JSON value from the provided string.
+// Numbers are decoded as json.Number to preserve their original format.
+func decodeJSON(content string) (interface{}, error) {
+	var data interface{}
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	// ensure there is no trailing data
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("invalid data after top-level value")
+	}
+	return data, nil
+}
+
+// flattenJSON flattens decoded JSON data into the values map. Keys of nested
+// objects and array indexes are joined with dots.
+func flattenJSON(values map[string]string, path string, data interface{}) {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			flattenJSON(values, joinPath(path, key), item)
+		}
+	case []interface{}:
+		for index, item := range v {
+			flattenJSON(values, joinPath(path, strconv.Itoa(index)), item)
+		}
+	case nil:
+		if path != "" {
+			values[path] = ""
+		}
+	default:
+		if path != "" {
+			values[path] = fmt.Sprint(v)
+		}
+	}
+}
+
+// joinPath joins a key to the dotted path.
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -1,7 +1,14 @@
 package app
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/piotr-ku/yaml-runner-go/system"
 	"github.com/stretchr/testify/assert"
@@ -15,6 +22,7 @@ type test struct {
 	name        string
 	facts       []Fact
 	expected    []string
+	rc          int
 	stdout      string
 	stderr      string
 	environment map[string]string
@@ -61,6 +69,7 @@ var tests = []test{
 			},
 		},
 		expected: []string{""},
+		rc:       1,
 		stdout:   empty,
 		stderr: "level=ERROR msg=\"fact gathered\" name=TEST3 " +
 			"command=\"echo test3 1>&2; exit 1;\" " +
@@ -68,17 +77,32 @@ var tests = []test{
 			"error=\"exit status 1\"",
 		environment: map[string]string{},
 	},
+	{
+		name: "Single fact with combined output",
+		facts: []Fact{
+			{
+				Name:          "TEST4",
+				Command:       "echo test4; echo test4 1>&2",
+				Shell:         "/bin/bash",
+				CombineOutput: true,
+			},
+		},
+		expected:    []string{"test4\ntest4"},
+		stdout:      empty,
+		stderr:      empty,
+		environment: map[string]string{"TEST4": "test4\ntest4"},
+	},
 }
 
 // TestGatherFacts tests the gatherFacts function.
 //
 // This function sets log settings and clears buffers, gathers facts from the
-// given test facts, tests the stdout, tests the logs, and tests the
-// environment.
+// given test facts, tests the stdout and the return code, tests the logs,
+// and tests the environment.
 func TestGatherFacts(t *testing.T) {
 	for _, test := range tests {
 		// Set log settings and clear buffers
-		system.LogInit(system.LogConfig{
+		_ = system.LogInit(system.LogConfig{
 			File:  "testing_buffer",
 			Level: "info",
 			Quiet: false,
@@ -86,11 +110,13 @@ func TestGatherFacts(t *testing.T) {
 		})
 
 		// Gather facts
-		facts := gatherFacts(test.facts)
+		facts := gatherFacts(context.Background(), test.facts)
 
-		// Test stdout
+		// Test stdout and return code
 		assert.Equal(t, test.expected[0],
 			facts[test.facts[0].Name].Result.Stdout)
+		assert.Equal(t, test.rc,
+			facts[test.facts[0].Name].Result.Rc)
 
 		// Test logs
 		assert.Regexp(t, test.stderr, system.GetTestingStderr())
@@ -100,3 +126,325 @@ func TestGatherFacts(t *testing.T) {
 		assert.Equal(t, test.environment, facts.toEnvironment())
 	}
 }
+
+// TestGatherFactsParseJSON tests the gatherFacts function with facts
+// parsing their output as JSON.
+//
+// It gathers facts with valid and invalid JSON output and verifies
+// the environment built from the parsed values and the logged warnings.
+func TestGatherFactsParseJSON(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		command     string
+		stdout      string
+		environment map[string]string
+	}{
+		{
+			name: "Nested objects and arrays",
+			command: `echo '{"name": "web", "state": {"code": 16, ` +
+				`"running": true}, "tags": ["a", "b"], "empty": null}'`,
+			stdout: empty,
+			environment: map[string]string{
+				"INSTANCE": `{"name": "web", "state": {"code": 16, ` +
+					`"running": true}, "tags": ["a", "b"], "empty": null}`,
+				"INSTANCE_name":          "web",
+				"INSTANCE_state.code":    "16",
+				"INSTANCE_state.running": "true",
+				"INSTANCE_tags.0":        "a",
+				"INSTANCE_tags.1":        "b",
+				"INSTANCE_empty":         "",
+			},
+		},
+		{
+			name:    "Invalid JSON",
+			command: "echo '{\"name\": '",
+			stdout: "level=WARN msg=\"fact parsing failed\" name=INSTANCE " +
+				"parse=json error=",
+			environment: map[string]string{"INSTANCE": "{\"name\": "},
+		},
+		{
+			name:    "Trailing data",
+			command: "echo '{} {}'",
+			stdout: "level=WARN msg=\"fact parsing failed\" name=INSTANCE " +
+				"parse=json error=\"invalid data after top-level value\"",
+			environment: map[string]string{"INSTANCE": "{} {}"},
+		},
+		{
+			name:        "Scalar value",
+			command:     "echo 42",
+			stdout:      empty,
+			environment: map[string]string{"INSTANCE": "42"},
+		},
+	} {
+		// Set log settings and clear buffers
+		_ = system.LogInit(system.LogConfig{
+			File:  "testing_buffer",
+			Level: "info",
+			Quiet: false,
+			JSON:  false,
+		})
+
+		// Gather facts
+		facts := gatherFacts(context.Background(), []Fact{
+			{Name: "INSTANCE", Command: test.command, Parse: "json"},
+		})
+
+		// Test logs and environment
+		assert.Regexp(t, test.stdout, system.GetTestingStdout(), test.name)
+		assert.Equal(t, test.environment, facts.toEnvironment(), test.name)
+	}
+}
+
+// TestGatherFactsCleanEnvironment tests the gatherFacts function with
+// facts executed in a clean or filtered environment.
+//
+// It verifies that the parent environment is passed only to facts without
+// the clean environment, and not passed if denied.
+func TestGatherFactsCleanEnvironment(t *testing.T) {
+	t.Setenv("YRG_PARENT_VAR", "relic-unzip-bonded")
+
+	// when: We gather facts with and without the clean environment
+	facts := gatherFacts(context.Background(), []Fact{
+		{Name: "parent", Command: "echo ${YRG_PARENT_VAR:-unset}"},
+		{Name: "clean", Command: "echo ${YRG_PARENT_VAR:-unset}",
+			CleanEnvironment: true},
+		{Name: "denied", Command: "echo ${YRG_PARENT_VAR:-unset}",
+			EnvDeny: []string{"YRG_PARENT_VAR"}},
+	})
+
+	// then: We check the fact values
+	assert.Equal(t, "relic-unzip-bonded", facts["parent"].Result.Stdout)
+	assert.Equal(t, "unset", facts["clean"].Result.Stdout)
+	assert.Equal(t, "unset", facts["denied"].Result.Stdout)
+}
+
+// TestGatherFactsWhen tests the gatherFacts function with conditional
+// facts.
+//
+// It verifies that facts are gathered only when their rule passes, and that
+// rules can refer to the facts gathered before.
+func TestGatherFactsWhen(t *testing.T) {
+	_ = system.LogInit(system.LogConfig{
+		File:  "testing_buffer",
+		Level: "debug",
+	})
+
+	// when: We gather conditional facts
+	facts := gatherFacts(context.Background(), []Fact{
+		{Name: "installed", Command: "echo yes"},
+		{Name: "applicable", Command: "echo status",
+			When: "[ \"${installed}\" = yes ]"},
+		{Name: "skipped", Command: "echo status",
+			When: "[ \"{{ .installed }}\" = no ]"},
+	})
+
+	// then: We check that only applicable facts were gathered
+	assert.Equal(t, "status", facts["applicable"].Result.Stdout)
+	assert.NotContains(t, facts, "skipped")
+	assert.Regexp(t, "level=DEBUG msg=\"fact skipped\" name=skipped",
+		system.GetTestingStdout())
+	assert.Equal(t, map[string]string{
+		"installed":  "yes",
+		"applicable": "status",
+	}, facts.toEnvironment())
+}
+
+// TestGatherFactsEnvName tests the gatherFacts function with facts
+// exporting their values under environment variable names different from
+// the fact names.
+func TestGatherFactsEnvName(t *testing.T) {
+	// when: We gather facts with environment names
+	facts := gatherFacts(context.Background(), []Fact{
+		{Name: "Apache Running Status", Command: "echo running",
+			EnvName: "APACHE_STATUS"},
+		{Name: "Apache Version", Command: `echo '{"major": 2}'`,
+			EnvName: "APACHE_VERSION", Parse: "json"},
+	})
+
+	// then: We check the facts and the environment
+	assert.Equal(t, "running", facts["Apache Running Status"].Result.Stdout)
+	assert.Equal(t, map[string]string{
+		"APACHE_STATUS":        "running",
+		"APACHE_VERSION":       `{"major": 2}`,
+		"APACHE_VERSION_major": "2",
+	}, facts.toEnvironment())
+}
+
+// TestGatherFactsFile tests the gatherFacts function with facts read from
+// files.
+//
+// It verifies that the file content is the trimmed fact value, kept raw
+// if requested, and that read errors are saved as the fact error.
+func TestGatherFactsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "version")
+	assert.Nil(t, os.WriteFile(file, []byte("1.2.3\n"), 0600))
+
+	// when: We gather facts from the files
+	facts := gatherFacts(context.Background(), []Fact{
+		{Name: "version", File: file},
+		{Name: "raw", File: file, RawOutput: true},
+		{Name: "missing", File: file + ".missing"},
+	})
+
+	// then: We check the fact values and errors
+	assert.Equal(t, "1.2.3", facts["version"].Result.Stdout)
+	assert.Equal(t, "1.2.3\n", facts["raw"].Result.Stdout)
+	assert.ErrorIs(t, facts["missing"].Result.Error, os.ErrNotExist)
+	assert.Equal(t, map[string]string{
+		"version": "1.2.3",
+		"raw":     "1.2.3\n",
+	}, facts.toEnvironment())
+}
+
+// TestGatherFactsRegex tests the gatherFacts function with facts filtered
+// by regular expressions.
+//
+// It verifies that the first capture group is the fact value, also
+// parsed as JSON if requested, and that an output not matching leaves
+// the value empty with a warning.
+func TestGatherFactsRegex(t *testing.T) {
+	_ = system.LogInit(system.LogConfig{
+		File:  "testing_buffer",
+		Level: "debug",
+	})
+
+	// when: We gather facts filtered by regular expressions
+	facts := gatherFacts(context.Background(), []Fact{
+		{Name: "load", Command: "echo 'load average: 0.42, 0.30'",
+			Regex: `load average: ([0-9.]+)`},
+		{Name: "status", Command: `echo 'status={"code": 3}'`,
+			Regex: `status=(.*)`, Parse: "json"},
+		{Name: "missing", Command: "echo nothing", Regex: `load (\d+)`},
+	})
+
+	// then: We check the filtered values
+	assert.Equal(t, "0.42", facts["load"].Result.Stdout)
+	assert.Equal(t, "3", facts["status"].Values["code"])
+	assert.Equal(t, "", facts["missing"].Result.Stdout)
+	assert.Regexp(t, `level=WARN msg="fact regex not matched" name=missing`,
+		system.GetTestingStdout())
+	assert.NotContains(t, facts.toEnvironment(), "missing")
+}
+
+// TestGatherFactsBool tests the gatherFacts function with boolean facts.
+//
+// It verifies that the fact value is "true" if the command succeeds and
+// "false" otherwise, regardless of its output, and that overridden values
+// are used as well.
+func TestGatherFactsBool(t *testing.T) {
+	FactOverrides = map[string]string{"overridden": "false"}
+	defer func() {
+		FactOverrides = nil
+	}()
+
+	// when: We gather boolean facts
+	facts := gatherFacts(context.Background(), []Fact{
+		{Name: "up", Command: "echo running", Type: "bool"},
+		{Name: "down", Command: "exit 3", Type: "bool"},
+		{Name: "overridden", Command: "true", Type: "bool"},
+	})
+
+	// then: We check the fact values
+	assert.Equal(t, map[string]string{
+		"up":         "true",
+		"down":       "false",
+		"overridden": "false",
+	}, facts.toEnvironment())
+}
+
+// TestGatherFactsHTTP tests the gatherFacts function with facts gathered
+// by HTTP probes.
+//
+// It verifies that the status code is the fact value, the fact succeeds
+// only with the expected status, the status code is kept as the return
+// code and exported as a value also if it is unexpected, the body is
+// exported if requested, and failed requests are saved as the fact error.
+func TestGatherFactsHTTP(t *testing.T) {
+	// given: We start an HTTP server
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+			_, _ = w.Write([]byte(r.Method + "\n"))
+		}))
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	// when: We gather the facts probing the server
+	facts := gatherFacts(context.Background(), []Fact{
+		{Name: "up", HTTP: &HTTPProbe{URL: server.URL, Body: true}},
+		{Name: "head", HTTP: &HTTPProbe{URL: server.URL, Method: "HEAD"}},
+		{Name: "missing", HTTP: &HTTPProbe{URL: server.URL + "/missing"}},
+		{Name: "expected", HTTP: &HTTPProbe{URL: server.URL + "/missing",
+			ExpectStatus: http.StatusNotFound}},
+		{Name: "down", HTTP: &HTTPProbe{URL: closed.URL, Timeout: "1s"}},
+	})
+	server.Close()
+
+	// then: We check the fact results
+	assert.Equal(t, "200", facts["up"].Result.Stdout)
+	assert.Equal(t, 0, facts["up"].Result.Rc)
+	assert.Equal(t, "HEAD "+server.URL, facts["head"].Result.Command)
+	assert.Equal(t, 404, facts["missing"].Result.Rc)
+	assert.EqualError(t, facts["missing"].Result.Error,
+		"unexpected status 404, expected 200")
+	assert.Equal(t, 1, facts["down"].Result.Rc)
+	assert.NotNil(t, facts["down"].Result.Error)
+	assert.Equal(t, map[string]string{
+		"up":              "200",
+		"up_status":       "200",
+		"up_body":         "GET",
+		"head":            "200",
+		"head_status":     "200",
+		"missing_status":  "404",
+		"expected":        "404",
+		"expected_status": "404",
+	}, facts.toEnvironment())
+}
+
+// TestGatherFactsStat tests the gatherFacts function with facts checking
+// paths.
+//
+// It verifies that existing files and directories are described by
+// the exported values and that missing paths are not an error.
+func TestGatherFactsStat(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockNow = func() time.Time {
+		return now
+	}
+	defer func() {
+		mockNow = time.Now
+	}()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	assert.Nil(t, os.WriteFile(file, []byte("content"), 0640))
+	mtime := now.Add(-time.Hour)
+	assert.Nil(t, os.Chtimes(file, mtime, mtime))
+
+	// when: We gather facts checking the paths
+	facts := gatherFacts(context.Background(), []Fact{
+		{Name: "file", Stat: file},
+		{Name: "dir", Stat: dir},
+		{Name: "missing", Stat: filepath.Join(dir, "missing")},
+	})
+
+	// then: We check the fact results
+	assert.Nil(t, facts["missing"].Result.Error)
+	environment := facts.toEnvironment()
+	for key, value := range map[string]string{
+		"file":           "true",
+		"file_exists":    "true",
+		"file_type":      "file",
+		"file_mode":      "0640",
+		"file_size":      "7",
+		"file_mtime":     strconv.FormatInt(mtime.Unix(), 10),
+		"file_age":       "3600",
+		"dir_type":       "dir",
+		"missing":        "false",
+		"missing_exists": "false",
+	} {
+		assert.Equal(t, value, environment[key], key)
+	}
+	assert.NotContains(t, environment, "missing_size")
+}
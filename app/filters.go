@@ -0,0 +1,92 @@
+package app
+
+import (
+	"slices"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// SkipFacts lists the names of the facts which are not gathered.
+var SkipFacts []string
+
+// SkipActions lists the names of the actions which are not executed.
+// Actions without a name are matched by their commands joined with "; ".
+var SkipActions []string
+
+// OnlyFacts lists the names of the only facts gathered, all facts are
+// gathered if empty. SkipFacts takes precedence.
+var OnlyFacts []string
+
+// OnlyActions lists the names of the only actions executed, all actions
+// are executed if empty. SkipActions takes precedence.
+var OnlyActions []string
+
+// Tags lists the tags selecting the facts and actions, all of them are
+// selected if empty. Facts and actions with a tag in Tags are selected,
+// facts and actions without tags are selected only if Tags contains
+// UntaggedTag.
+var Tags []string
+
+// UntaggedTag is the tag selecting the facts and actions without tags.
+const UntaggedTag = "untagged"
+
+// Filtered returns the configuration with the facts and actions filtered
+// by SkipFacts, SkipActions, OnlyFacts, OnlyActions and Tags.
+func (c Config) Filtered() Config {
+	filtered := c
+	filtered.Facts = filterFacts(c.Facts)
+	filtered.Actions = filterActions(c.Actions)
+	return filtered
+}
+
+// filterFacts returns the facts selected by OnlyFacts and Tags and not
+// skipped by SkipFacts. Each skipped fact is logged.
+func filterFacts(facts []Fact) []Fact {
+	filtered := []Fact{}
+	for _, fact := range facts {
+		if !selected(fact.Name, OnlyFacts, SkipFacts) || !tagged(fact.Tags) {
+			system.Log("info", "skipping fact", "name", fact.Name)
+			continue
+		}
+		filtered = append(filtered, fact)
+	}
+	return filtered
+}
+
+// filterActions returns the actions selected by OnlyActions and Tags and
+// not skipped by SkipActions. Each skipped action is logged.
+func filterActions(actions []Action) []Action {
+	filtered := []Action{}
+	for _, action := range actions {
+		if !selected(action.name(), OnlyActions, SkipActions) ||
+			!tagged(action.Tags) {
+			system.Log("info", "skipping action", "name", action.name())
+			continue
+		}
+		filtered = append(filtered, action)
+	}
+	return filtered
+}
+
+// selected reports whether the name is not skipped and is listed in only,
+// or only is empty.
+func selected(name string, only []string, skip []string) bool {
+	if slices.Contains(skip, name) {
+		return false
+	}
+	return len(only) == 0 || slices.Contains(only, name)
+}
+
+// tagged reports whether the tags intersect Tags, or Tags is empty. Empty
+// tags are selected by UntaggedTag.
+func tagged(tags []string) bool {
+	if len(Tags) == 0 {
+		return true
+	}
+	if len(tags) == 0 {
+		return slices.Contains(Tags, UntaggedTag)
+	}
+	return slices.ContainsFunc(tags, func(tag string) bool {
+		return slices.Contains(Tags, tag)
+	})
+}
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRunFilters tests skipping and selecting facts and actions by name.
+//
+// It verifies that skipped facts are not gathered, that actions are
+// matched by their name or commands, that skipping takes precedence over
+// selecting and that every skipped item is logged.
+func TestRunFilters(t *testing.T) {
+	defer func() {
+		SkipFacts, SkipActions, OnlyFacts, OnlyActions = nil, nil, nil, nil
+		Tags = nil
+	}()
+	config := Config{
+		Facts: []Fact{
+			{Name: "load", Command: "echo 3"},
+			{Name: "zone", Command: "echo eu"},
+			{Name: "user", Command: "echo root"},
+		},
+		Actions: []Action{
+			{Name: "restart", Command: "echo restart"},
+			{Command: "echo reload"},
+			{Name: "notify", Command: "echo notify"},
+		},
+	}
+	SkipFacts, OnlyFacts = []string{"zone"}, []string{"zone", "user"}
+	SkipActions = []string{"echo reload"}
+	OnlyActions = []string{"restart", "echo reload"}
+	_ = system.LogInit(system.LogConfig{
+		File:  "testing_buffer",
+		Level: "info",
+	})
+
+	// when: We run the filtered configuration
+	results, err := run(context.Background(), config.Filtered())
+
+	// then: We check the gathered facts and the executed actions
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"user"}, factNames(results.Facts))
+	assert.Len(t, results.Actions, 1)
+	assert.Equal(t, "restart", results.Actions[0].Action.Name)
+	output := system.GetTestingStdout()
+	for _, skipped := range []string{"fact\" name=load", "fact\" name=zone",
+		"action\" name=\"echo reload\"", "action\" name=notify"} {
+		assert.Contains(t, output, "msg=\"skipping "+skipped)
+	}
+}
+
+// TestRunTags tests selecting facts and actions by tags.
+//
+// It verifies that only the items with a selected tag are gathered and
+// executed, and that items without tags are selected by the untagged tag.
+func TestRunTags(t *testing.T) {
+	defer func() { Tags = nil }()
+	config := Config{
+		Facts: []Fact{
+			{Name: "version", Command: "echo 1.0", Tags: []string{"deploy"}},
+			{Name: "disk", Command: "echo 90", Tags: []string{"maintenance"}},
+			{Name: "zone", Command: "echo eu"},
+		},
+		Actions: []Action{
+			{Command: "echo deploy", Tags: []string{"deploy", "release"}},
+			{Command: "echo cleanup", Tags: []string{"maintenance"}},
+			{Command: "echo notify"},
+		},
+	}
+
+	for _, test := range []struct {
+		Tags    []string
+		Facts   []string
+		Actions []string
+	}{
+		{Tags: nil, Facts: []string{"disk", "version", "zone"},
+			Actions: []string{"echo deploy", "echo cleanup", "echo notify"}},
+		{Tags: []string{"release"}, Facts: []string{},
+			Actions: []string{"echo deploy"}},
+		{Tags: []string{"deploy", UntaggedTag},
+			Facts:   []string{"version", "zone"},
+			Actions: []string{"echo deploy", "echo notify"}},
+	} {
+		Tags = test.Tags
+
+		// when: We run the filtered configuration
+		results, err := run(context.Background(), config.Filtered())
+
+		// then: We check the gathered facts and the executed actions
+		assert.Nil(t, err)
+		names := factNames(results.Facts)
+		slices.Sort(names)
+		assert.Equal(t, test.Facts, names, test.Tags)
+		actions := []string{}
+		for _, action := range results.Actions {
+			actions = append(actions, action.Action.name())
+		}
+		assert.Equal(t, test.Actions, actions, test.Tags)
+	}
+}
+
+// factNames returns the names of the facts.
+func factNames(facts Facts) []string {
+	names := []string{}
+	for name := range facts {
+		names = append(names, name)
+	}
+	return names
+}
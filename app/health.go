@@ -0,0 +1,72 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Health collects the status of the daemon runs served by the health
+// HTTP endpoint.
+type Health struct {
+	mutex     sync.RWMutex
+	runs      int
+	lastRun   time.Time
+	duration  time.Duration
+	lastError error
+}
+
+// HealthStatus provides a data format for the last run status served
+// by the health HTTP endpoint.
+type HealthStatus struct {
+	Runs            int       `json:"runs"`
+	LastRun         time.Time `json:"last_run"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	LastError       string    `json:"last_error"`
+}
+
+// NewHealth creates a new Health instance.
+func NewHealth() *Health {
+	return &Health{}
+}
+
+// Record saves the status of a finished run.
+func (h *Health) Record(start time.Time, duration time.Duration, err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.runs++
+	h.lastRun = start
+	h.duration = duration
+	h.lastError = err
+}
+
+// Status returns the status of the last run.
+func (h *Health) Status() HealthStatus {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	status := HealthStatus{
+		Runs:            h.runs,
+		LastRun:         h.lastRun,
+		DurationSeconds: h.duration.Seconds(),
+	}
+	if h.lastError != nil {
+		status.LastError = h.lastError.Error()
+	}
+	return status
+}
+
+// Handler returns an HTTP handler serving the liveness probe on /healthz
+// and the last run status in JSON format on /status.
+func (h *Health) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(h.Status())
+	})
+	return mux
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHealthHandler tests the health HTTP handler.
+//
+// It records runs and verifies the responses of the liveness probe
+// and the last run status endpoints.
+func TestHealthHandler(t *testing.T) {
+	health := NewHealth()
+	handler := health.Handler()
+
+	// then: We check the liveness probe
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+		"/healthz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "ok\n", recorder.Body.String())
+
+	// when: We record two runs
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	health.Record(start, time.Second, nil)
+	health.Record(start.Add(time.Minute), 2*time.Second,
+		errors.New("stiffen-uncouple-rehab"))
+
+	// then: We check the last run status
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+		"/status", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var status HealthStatus
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.Equal(t, HealthStatus{
+		Runs:            2,
+		LastRun:         start.Add(time.Minute),
+		DurationSeconds: 2,
+		LastError:       "stiffen-uncouple-rehab",
+	}, status)
+
+	// then: We check that a successful run clears the last error
+	health.Record(start, time.Second, nil)
+	assert.Equal(t, "", health.Status().LastError)
+}
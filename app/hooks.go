@@ -0,0 +1,36 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// runHook executes a hook command defined in the configuration file,
+// e.g. the command run before gathering facts, in the working directory
+// if set. An empty command is skipped. It returns an error if the command
+// fails.
+func runHook(ctx context.Context, hook string, command string,
+	directory string) error {
+	if command == "" {
+		return nil
+	}
+
+	c := system.NewCommand(command)
+	if directory != "" {
+		c.Directory = directory
+	}
+	err := c.Execute(ctx)
+	logHookExecuted(hook, &c)
+	if err != nil {
+		return fmt.Errorf("%s hook failed: %w", hook, err)
+	}
+	return nil
+}
+
+// logHookExecuted logs the execution of a hook command.
+func logHookExecuted(hook string, c *system.Command) {
+	commandLogger(c, "hook", hook).Log(commandLogLevel(c, "info"),
+		"hook executed")
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// RuleCheck provides the result of an evaluated rule.
+type RuleCheck struct {
+	Rule   string         // rule defined in the configuration
+	Result system.Command // executed rule command with its output
+	Passed bool           // whether the rule command finished with zero
+	// error of the rule itself, e.g. a syntax error, as opposed to the rule
+	// not passing
+	Error error
+}
+
+// ActionLint provides the result of linting an action: its evaluated rules
+// and whether it would be executed.
+type ActionLint struct {
+	Action Action      // action defined in the configuration
+	Rules  []RuleCheck // evaluated rules in configuration order
+	Fires  bool        // whether the rules and conditions pass
+}
+
+// Errors returns the errors of the rules of the action.
+func (a ActionLint) Errors() []error {
+	errs := []error{}
+	for _, check := range a.Rules {
+		if check.Error != nil {
+			errs = append(errs, fmt.Errorf("rule %q: %w", check.Rule,
+				check.Error))
+		}
+	}
+	return errs
+}
+
+// Lint gathers the facts and evaluates the rules and conditions of all
+// actions without executing any action command. Unlike a run, all rules
+// of an action are evaluated, so errors of every rule are reported.
+// Rules referencing undefined facts are logged as warnings.
+// Rules of actions without a directory are executed in the default
+// directory, or in the working directory of the configuration.
+// The shells check, the before and after hooks and the run timeout are
+// applied as in GatherFacts. It returns a ValidationError if a shell is
+// missing and an OSError if the before hook fails.
+func Lint(ctx context.Context, config Config) ([]ActionLint, error) {
+	logUndefinedReferences(config)
+	facts, err := GatherFacts(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	environment := facts.toEnvironment()
+
+	directory := defaultValue(config.Defaults.Directory, config.WorkDir)
+	results := []ActionLint{}
+	for _, action := range config.Actions {
+		action.Directory = defaultValue(action.Directory, directory)
+		results = append(results, lintAction(ctx, action, environment))
+	}
+	return results, nil
+}
+
+// lintAction evaluates all rules and conditions of the action.
+func lintAction(ctx context.Context, action Action,
+	environment map[string]string) ActionLint {
+	result := ActionLint{Action: action, Fires: true}
+	for _, rule := range action.Rules {
+		check := evaluateRule(ctx, rule, environment, action)
+		result.Rules = append(result.Rules, check)
+		result.Fires = result.Fires && check.Passed
+	}
+	for _, condition := range action.Conditions {
+		result.Fires = result.Fires && condition.check(environment)
+	}
+	return result
+}
+
+// evaluateRule renders the rule of the action with the fact values if
+// templating is enabled and executes it with the rule timeout of the action
+// in the working directory of the action.
+// The fact values and the rule environment of the action, which takes
+// precedence, are set as environment variables. A rule which cannot be
+// rendered does not pass.
+func evaluateRule(ctx context.Context, rule string,
+	environment map[string]string, action Action) RuleCheck {
+	command, err := action.render(rule, environment)
+	if err != nil {
+		logRenderFailed(rule, err)
+		return RuleCheck{Rule: rule, Error: err}
+	}
+
+	c := system.NewCommand(command)
+	c.Environment = maps.Clone(environment)
+	maps.Copy(c.Environment, action.ruleEnvironment())
+	setCommandOptions(&c, action.RuleTimeout, action.Directory, nil)
+	_ = c.Execute(ctx)
+	logRuleChecked(&c)
+	return RuleCheck{Rule: rule, Result: c, Passed: c.Rc == 0,
+		Error: ruleError(&c)}
+}
+
+// ruleError returns the error of the executed rule command itself: rules
+// report passing with the return code 0 and not passing with 1, while
+// higher return codes are used by shells and test(1) for errors, e.g.
+// syntax errors or commands not found. Rules which could not be started
+// or timed out are errors as well.
+func ruleError(c *system.Command) error {
+	switch {
+	case c.Rc == 0 || c.Rc == 1:
+		return nil
+	case c.TimedOut:
+		return errors.New("rule timed out")
+	case c.Stderr != "":
+		return fmt.Errorf("return code %d: %s", c.Rc, c.Stderr)
+	case c.Error != nil:
+		return c.Error
+	}
+	return fmt.Errorf("return code %d", c.Rc)
+}
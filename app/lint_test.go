@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLint tests the Lint function.
+//
+// It verifies that all rules are evaluated without executing actions,
+// that the actions whose rules and conditions pass would fire, and that
+// only rules which error, as opposed to rules not passing, are reported.
+func TestLint(t *testing.T) {
+	// given: We define actions with passing, failing and broken rules
+	file := filepath.Join(t.TempDir(), "executed")
+	config := Config{
+		Facts: []Fact{{Name: "count", Command: "echo 5"}},
+		Actions: []Action{
+			{Command: "touch " + file, Rules: []string{"[ ${count} -gt 3 ]"}},
+			{Command: "touch " + file, Rules: []string{"[ ${count} -gt 9 ]",
+				"[ ${count} -eq ]"}},
+			{Command: "touch " + file, Rules: []string{"{{ .count "},
+				Template: true},
+			{Command: "touch " + file, Conditions: []Condition{
+				{Fact: "count", Matches: "^4$"}}},
+		},
+	}
+
+	// when: We lint the configuration
+	results, err := Lint(context.Background(), config)
+
+	// then: We check the linted actions
+	assert.Nil(t, err)
+	assert.NoFileExists(t, file)
+	assert.Len(t, results, 4)
+	assert.True(t, results[0].Fires)
+	assert.Empty(t, results[0].Errors())
+	assert.False(t, results[1].Fires)
+	assert.Len(t, results[1].Rules, 2)
+	assert.Nil(t, results[1].Rules[0].Error)
+	assert.Equal(t, 2, results[1].Rules[1].Result.Rc)
+	assert.Len(t, results[1].Errors(), 1)
+	assert.False(t, results[2].Fires)
+	assert.Len(t, results[2].Errors(), 1)
+	assert.False(t, results[3].Fires)
+	assert.Empty(t, results[3].Errors())
+}
+
+// TestLintRuleDirectory tests the working directory of the rules.
+//
+// It verifies that rules are executed in the directory of the action,
+// or in the working directory of the configuration, and fact conditions
+// in the directory of the fact.
+func TestLintRuleDirectory(t *testing.T) {
+	// given: We define directories with marker files
+	workDir, actionDir := t.TempDir(), t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(workDir, "workdir"), nil, 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(actionDir, "action"), nil, 0600))
+	config := Config{
+		WorkDir: workDir,
+		Facts: []Fact{{Name: "marker", Command: "echo found",
+			When: "[ -e action ]", Directory: actionDir}},
+		Actions: []Action{
+			{Command: "true", Rules: []string{"[ -e workdir ]"}},
+			{Command: "true", Rules: []string{"[ -e action ]"},
+				Directory: actionDir},
+		},
+	}
+
+	// when: We lint the configuration
+	results, err := Lint(context.Background(), config)
+
+	// then: We check that the rules passed in their directories
+	assert.Nil(t, err)
+	assert.True(t, results[0].Fires)
+	assert.True(t, results[1].Fires)
+
+	// when: We gather the facts
+	facts, err := GatherFacts(context.Background(), config)
+
+	// then: We check that the fact condition passed in the directory
+	assert.Nil(t, err)
+	assert.Equal(t, "found", facts["marker"].Result.Stdout)
+}
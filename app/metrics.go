@@ -0,0 +1,88 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics collects Prometheus metrics of runs, facts and actions.
+type Metrics struct {
+	registry           *prometheus.Registry
+	runs               prometheus.Counter
+	actionExecutions   *prometheus.CounterVec
+	factGatherDuration prometheus.Histogram
+	actionDuration     prometheus.Histogram
+}
+
+// metrics holds the enabled metrics. Metrics are not collected when nil.
+var metrics *Metrics
+
+// EnableMetrics creates a new metrics registry and enables collecting
+// metrics in the following runs.
+func EnableMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		runs: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "runs_total",
+			Help: "Total number of runs.",
+		}),
+		actionExecutions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "action_executions_total",
+			Help: "Total number of action executions by result.",
+		}, []string{"result"}),
+		factGatherDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "fact_gather_duration_seconds",
+			Help: "Duration of fact commands in seconds.",
+		}),
+		actionDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "action_duration_seconds",
+			Help: "Duration of action commands in seconds.",
+		}),
+	}
+	m.registry.MustRegister(m.runs, m.actionExecutions,
+		m.factGatherDuration, m.actionDuration)
+
+	metrics = m
+	return m
+}
+
+// DisableMetrics stops collecting metrics.
+func DisableMetrics() {
+	metrics = nil
+}
+
+// Handler returns an HTTP handler serving the metrics in the Prometheus
+// exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// observeRun counts a run.
+func (m *Metrics) observeRun() {
+	if m == nil {
+		return
+	}
+	m.runs.Inc()
+}
+
+// observeFactGathered records the duration of a fact command.
+func (m *Metrics) observeFactGathered(duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.factGatherDuration.Observe(duration.Seconds())
+}
+
+// observeActionExecuted counts an action execution by its result and
+// records the duration of the action command.
+func (m *Metrics) observeActionExecuted(duration time.Duration,
+	result string) {
+	if m == nil {
+		return
+	}
+	m.actionExecutions.WithLabelValues(result).Inc()
+	m.actionDuration.Observe(duration.Seconds())
+}
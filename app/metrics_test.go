@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMetrics tests collecting and serving the Prometheus metrics.
+//
+// It enables metrics, gathers facts and executes actions, and verifies
+// the metrics served by the metrics HTTP handler. Then it disables
+// metrics and verifies that no more metrics are collected.
+func TestMetrics(t *testing.T) {
+	// Set log settings and clear buffers
+	_ = system.LogInit(system.LogConfig{
+		File:  "testing_buffer",
+		Level: "info",
+		Quiet: false,
+		JSON:  false,
+	})
+
+	// given: We enable metrics
+	m := EnableMetrics()
+	defer DisableMetrics()
+
+	// when: We gather facts and execute actions
+	ctx := context.Background()
+	m.observeRun()
+	facts := gatherFacts(ctx, []Fact{
+		{Name: "TEST1", Command: "echo test1"},
+	})
+	executeActions(ctx, []Action{
+		{Command: "echo action 1"},
+		{Command: "echo action 2"},
+		{Command: "exit 1"},
+	}, facts)
+
+	// then: We check the served metrics
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+		"/metrics", nil))
+	body := recorder.Body.String()
+	assert.Contains(t, body, "runs_total 1\n")
+	assert.Contains(t, body, "action_executions_total{result=\"success\"} 2\n")
+	assert.Contains(t, body, "action_executions_total{result=\"failure\"} 1\n")
+	assert.Contains(t, body, "fact_gather_duration_seconds_count 1\n")
+	assert.Contains(t, body, "action_duration_seconds_count 3\n")
+
+	// then: We check that disabled metrics are not collected
+	DisableMetrics()
+	metrics.observeRun()
+	metrics.observeFactGathered(0)
+	metrics.observeActionExecuted(0, "success")
+	recorder = httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+		"/metrics", nil))
+	assert.Contains(t, recorder.Body.String(), "runs_total 1\n")
+}
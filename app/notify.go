@@ -0,0 +1,97 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// notifyTimeout is the timeout of a failure notification request, so
+// an unresponsive webhook cannot stall the run.
+const notifyTimeout = 5 * time.Second
+
+// failureNotification is the JSON payload posted to the notify URL
+// for a failed action.
+type failureNotification struct {
+	Action  string `json:"action"`
+	Command string `json:"command"`
+	Rc      int    `json:"rc"`
+	Stderr  string `json:"stderr"`
+}
+
+// notifyFailures posts a notification for each failed action to the URL
+// concurrently and waits for the requests to finish. Notifications are
+// best-effort: failed requests are logged as warnings. Nothing is sent if
+// the URL is empty.
+func notifyFailures(ctx context.Context, url string, results []ActionResult) {
+	if url == "" {
+		return
+	}
+	// Send the notifications also if the run timed out or was cancelled
+	notifyCtx := context.WithoutCancel(ctx)
+	var wg sync.WaitGroup
+	for _, result := range results {
+		if !result.Failed() {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			notifyFailure(notifyCtx, url, result)
+		}()
+	}
+	wg.Wait()
+}
+
+// notifyFailure posts the notification about the failed action to the URL
+// and logs the result.
+func notifyFailure(ctx context.Context, url string, result ActionResult) {
+	notification := failureNotification{
+		Action:  result.Action.name(),
+		Command: strings.Join(result.Action.CommandList(), "; "),
+		Rc:      result.Result.Rc,
+		Stderr:  result.Result.Stderr,
+	}
+	if err := postJSON(ctx, url, notification); err != nil {
+		system.Log("warn", "failure notification failed", "action",
+			notification.Action, "error", err)
+		return
+	}
+	system.Log("debug", "failure notification sent", "action",
+		notification.Action)
+}
+
+// postJSON posts the payload as JSON to the URL with notifyTimeout. It
+// returns an error if the request fails or the response status is not
+// successful.
+func postJSON(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url,
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	return nil
+}
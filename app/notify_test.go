@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExecuteNotifyFailures tests the notifications about failed actions.
+//
+// It verifies that a JSON payload is posted for every failed action only,
+// and that failed notifications are logged without failing the run.
+func TestExecuteNotifyFailures(t *testing.T) {
+	var mutex sync.Mutex
+	received := []failureNotification{}
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			var notification failureNotification
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&notification))
+			mutex.Lock()
+			defer mutex.Unlock()
+			received = append(received, notification)
+		}))
+	defer server.Close()
+	config := Config{
+		NotifyURL: server.URL,
+		Actions: []Action{
+			{Name: "stop", Command: "echo stopping >&2; exit 3"},
+			{Command: "true"},
+			{Command: "exit 1", IgnoreErrors: true},
+		},
+	}
+
+	// when: We execute the configuration
+	_, err := Execute(context.Background(), config)
+
+	// then: We check the posted notifications
+	assert.Nil(t, err)
+	assert.Equal(t, []failureNotification{{Action: "stop",
+		Command: "echo stopping >&2; exit 3", Rc: 3, Stderr: "stopping"}},
+		received)
+
+	// given: We define an unavailable webhook
+	server.Close()
+	_ = system.LogInit(system.LogConfig{
+		File:  "testing_buffer",
+		Level: "warn",
+	})
+
+	// when: We execute the configuration
+	_, err = Execute(context.Background(), config)
+
+	// then: We check the logged notification failure
+	assert.Nil(t, err)
+	assert.Regexp(t, `level=WARN msg="failure notification failed" `+
+		`action=stop error=`, system.GetTestingStdout())
+}
@@ -0,0 +1,48 @@
+package app
+
+import "path/filepath"
+
+// configDir returns the directory of the configuration file, or an empty
+// string if the configuration is read from the standard input or a URL.
+func configDir(file string) string {
+	if file == "-" || isURL(file) {
+		return ""
+	}
+	return filepath.Dir(file)
+}
+
+// resolveOutputFiles resolves the relative output files of the facts
+// and actions, and the fact files and paths, against the directory. Paths
+// are left unchanged if the directory is empty.
+func (c *Config) resolveOutputFiles(dir string) {
+	for i := range c.Facts {
+		fact := &c.Facts[i]
+		fact.StdoutFile = resolvePath(dir, fact.StdoutFile)
+		fact.StderrFile = resolvePath(dir, fact.StderrFile)
+		fact.File = resolvePath(dir, fact.File)
+		fact.Stat = resolvePath(dir, fact.Stat)
+	}
+	for i := range c.Actions {
+		action := &c.Actions[i]
+		action.StdoutFile = resolvePath(dir, action.StdoutFile)
+		action.StderrFile = resolvePath(dir, action.StderrFile)
+	}
+}
+
+// resolvePath joins the relative path with the directory. Empty
+// and absolute paths are returned unchanged.
+func resolvePath(dir string, path string) string {
+	if dir == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// outputLogParams returns the log parameters of a command output:
+// the path of the file the output was written to, or the output itself.
+func outputLogParams(name string, output string, file string) []interface{} {
+	if file != "" {
+		return []interface{}{name + "_file", file}
+	}
+	return []interface{}{name, output}
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLoadConfigFileOutputFiles tests resolving the output files
+// of facts and actions relative to the configuration directory.
+func TestLoadConfigFileOutputFiles(t *testing.T) {
+	// given: We define a configuration file with output files
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	content := []byte(`
+        facts:
+          - name: fact
+            command: echo fact
+            stdout_file: fact.out
+          - name: version
+            file: /etc/version
+          - name: release
+            file: release.txt
+        actions:
+          - command: echo action
+            stdout_file: /var/log/action.out
+            stderr_file: logs/action.err
+    `)
+	assert.Nil(t, os.WriteFile(file, content, 0600))
+
+	// when: We load the configuration file
+	config, err := LoadConfigFile(file)
+
+	// then: We check the resolved paths
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "fact.out"), config.Facts[0].StdoutFile)
+	assert.Equal(t, "", config.Facts[0].StderrFile)
+	assert.Equal(t, "/etc/version", config.Facts[1].File)
+	assert.Equal(t, filepath.Join(dir, "release.txt"), config.Facts[2].File)
+	assert.Equal(t, "/var/log/action.out", config.Actions[0].StdoutFile)
+	assert.Equal(t, filepath.Join(dir, "logs/action.err"),
+		config.Actions[0].StderrFile)
+	assert.Equal(t, "", configDir("-"))
+	assert.Equal(t, "", configDir("https://example.com/config.yaml"))
+}
+
+// TestExecuteActionsOutputFiles tests executing an action writing its
+// output to a file.
+//
+// It verifies that the output is written to the file and the log contains
+// the file path instead of the output.
+func TestExecuteActionsOutputFiles(t *testing.T) {
+	_ = system.LogInit(system.LogConfig{
+		File:  "testing_buffer",
+		Level: "debug",
+	})
+	stdoutFile := filepath.Join(t.TempDir(), "action.out")
+
+	// when: We execute an action writing its output to a file
+	executeActions(context.Background(), []Action{
+		{Command: "echo large-$((40+2))", StdoutFile: stdoutFile},
+	}, Facts{})
+
+	// then: We check the file and the log
+	content, _ := os.ReadFile(stdoutFile)
+	assert.Equal(t, "large-42\n", string(content))
+	assert.Regexp(t, "rc=0 stdout_file=[^ ]+action.out stderr=\"\"",
+		system.GetTestingStdout())
+	assert.NotContains(t, system.GetTestingStdout(), "large-42")
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// FactOverrides maps fact names to values used instead of executing
+// the fact commands, e.g. to test action rules against arbitrary values.
+var FactOverrides map[string]string
+
+// ParseFactOverrides parses fact overrides in the NAME=VALUE format.
+// It returns a ValidationError if an override has no name.
+func ParseFactOverrides(overrides []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, override := range overrides {
+		name, value, found := strings.Cut(override, "=")
+		if !found || name == "" {
+			return nil, system.WrapError(&ValidationError{Err: fmt.Errorf(
+				"invalid fact override %q, expected NAME=VALUE", override)})
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// overrideFact returns the fact with the overridden value as its result.
+// Boolean facts succeed if the value is true, e.g. "true" or "1".
+// It returns false if the fact value is not overridden.
+func overrideFact(fact Fact) (Fact, bool) {
+	value, ok := FactOverrides[fact.Name]
+	if !ok {
+		return fact, false
+	}
+	fact.Result = system.Command{Command: fact.Command, Stdout: value}
+	if succeeded, _ := strconv.ParseBool(value); fact.Type == "bool" &&
+		!succeeded {
+		fact.Result.Rc = 1
+	}
+	fact.parseOutput()
+	system.Log("debug", "fact overridden", "name", fact.Name,
+		"value", value)
+	return fact, true
+}
+
+// addOverriddenFacts adds the overridden facts which are not defined
+// in the configuration to the gathered facts.
+func addOverriddenFacts(gatheredFacts Facts) {
+	for name := range FactOverrides {
+		if _, exists := gatheredFacts[name]; !exists {
+			gatheredFacts[name], _ = overrideFact(Fact{Name: name})
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseFactOverrides tests parsing fact overrides in the NAME=VALUE
+// format.
+func TestParseFactOverrides(t *testing.T) {
+	// when: We parse valid overrides
+	overrides, err := ParseFactOverrides([]string{
+		"loadAverage1=20", "empty=", "equation=a=b",
+	})
+
+	// then: We check the parsed values
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"loadAverage1": "20",
+		"empty":        "",
+		"equation":     "a=b",
+	}, overrides)
+
+	// then: We check that overrides without a name are rejected
+	for _, override := range []string{"loadAverage1", "=20"} {
+		_, err = ParseFactOverrides([]string{override})
+		assertErrorName(t, "ValidationError", err)
+	}
+}
+
+// TestGatherFactsWithOverrides tests gathering facts with overridden values.
+//
+// It verifies that the overridden facts are not executed, and that
+// overrides of facts not defined in the configuration are added.
+func TestGatherFactsWithOverrides(t *testing.T) {
+	FactOverrides = map[string]string{
+		"overridden": `{"code": 20}`,
+		"synthetic":  "pouch-unbent-grimace",
+	}
+	defer func() {
+		FactOverrides = nil
+	}()
+	_ = system.LogInit(system.LogConfig{
+		File:  "testing_buffer",
+		Level: "debug",
+	})
+
+	// when: We gather the facts
+	facts := gatherFacts(context.Background(), []Fact{
+		{Name: "overridden", Command: "echo executed", Parse: "json"},
+		{Name: "executed", Command: "echo executed"},
+	})
+
+	// then: We check the fact values
+	assert.Equal(t, `{"code": 20}`, facts["overridden"].Result.Stdout)
+	assert.Equal(t, "20", facts["overridden"].Values["code"])
+	assert.Equal(t, "executed", facts["executed"].Result.Stdout)
+	assert.Equal(t, "pouch-unbent-grimace", facts["synthetic"].Result.Stdout)
+	assert.Regexp(t, "level=DEBUG msg=\"fact overridden\" name=overridden",
+		system.GetTestingStdout())
+	assert.NotRegexp(t, "msg=\"fact gathered\" name=overridden",
+		system.GetTestingStdout())
+}
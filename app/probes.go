@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// HTTPProbe provides a data format for facts gathered by an HTTP request
+// instead of a command.
+type HTTPProbe struct {
+	URL    string `validate:"required,url"` // requested URL
+	Method string `validate:"omitempty,oneof=GET HEAD POST PUT DELETE"`
+	// expected status code, 200 by default
+	ExpectStatus int `yaml:"expect_status" validate:"omitempty,gte=100,lte=599"`
+	// request timeout, e.g. "1s", 5s by default
+	Timeout string `validate:"duration"`
+	// export the response body as the <name>_body value
+	Body bool
+}
+
+// defaultProbeTimeout is the timeout of HTTP probes without a timeout set.
+const defaultProbeTimeout = 5 * time.Second
+
+// probe performs the HTTP request and returns its result: the status code
+// as the output and the return code 0 if the status is expected or
+// the status code itself if not. Requests which fail, e.g. because
+// the connection is refused, get the return code 1 and the error. It also
+// returns the response body.
+func (p *HTTPProbe) probe(ctx context.Context) (system.Command, string) {
+	result := system.Command{Command: p.method() + " " + p.URL}
+	timeout, err := parseDuration(p.Timeout)
+	if err != nil || timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, p.method(), p.URL, nil)
+	if err != nil {
+		return probeFailed(result, err), ""
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return probeFailed(result, err), ""
+	}
+	defer response.Body.Close()
+	return p.probeResult(result, response)
+}
+
+// probeFailed returns the result of a failed request.
+func probeFailed(result system.Command, err error) system.Command {
+	result.Rc = 1
+	result.Error = err
+	return result
+}
+
+// probeResult returns the result of the probe with the response.
+func (p *HTTPProbe) probeResult(result system.Command,
+	response *http.Response) (system.Command, string) {
+	body, err := io.ReadAll(response.Body)
+	result.Stdout = strconv.Itoa(response.StatusCode)
+	result.Error = err
+	if response.StatusCode != p.expectStatus() {
+		result.Rc = response.StatusCode
+		result.Error = fmt.Errorf("unexpected status %d, expected %d",
+			response.StatusCode, p.expectStatus())
+	}
+	return result, string(body)
+}
+
+// values returns the values exported by the probe: the status code of
+// the response, also if it is unexpected, and the body if requested.
+func (p *HTTPProbe) values(result system.Command,
+	body string) map[string]string {
+	values := make(map[string]string)
+	if result.Stdout != "" {
+		values["status"] = result.Stdout
+	}
+	if p.Body {
+		values["body"] = body
+	}
+	return values
+}
+
+// method returns the request method, GET by default.
+func (p *HTTPProbe) method() string {
+	if p.Method == "" {
+		return http.MethodGet
+	}
+	return p.Method
+}
+
+// expectStatus returns the expected status code, 200 by default.
+func (p *HTTPProbe) expectStatus() int {
+	if p.ExpectStatus == 0 {
+		return http.StatusOK
+	}
+	return p.ExpectStatus
+}
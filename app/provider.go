@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// gatherConfigFacts gathers the facts defined in the configuration
+// and adds the facts output by the fact provider.
+func gatherConfigFacts(ctx context.Context, config Config) Facts {
+	facts := gatherFacts(ctx, config.Facts)
+	if config.Provider != "" && ctx.Err() == nil {
+		addProviderFacts(ctx, facts, config)
+	}
+	return facts
+}
+
+// addProviderFacts executes the fact provider command with the default
+// command settings and adds the facts it outputs to the gathered facts.
+// Facts already gathered, including the overridden ones, are not replaced,
+// and facts not selected by SkipFacts, OnlyFacts and Tags are not added.
+// If the provider fails or its output cannot be parsed, an error is logged
+// and no facts are added.
+func addProviderFacts(ctx context.Context, gatheredFacts Facts,
+	config Config) {
+	provider := config.Provider
+	c := system.NewCommand(provider)
+	c.Shell = defaultValue(config.Defaults.Shell, c.Shell)
+	setCommandOptions(&c, config.Defaults.Timeout,
+		defaultValue(config.Defaults.Directory, config.WorkDir),
+		config.Defaults.Environment)
+	_ = c.Execute(ctx)
+	logger := commandLogger(&c)
+	if c.Error != nil || c.Rc != 0 {
+		logger.Log("error", "fact provider failed")
+		return
+	}
+	values, err := parseProviderOutput(c.Stdout)
+	if err != nil {
+		logger.Log("error", "fact provider output invalid", "parse_error", err)
+		return
+	}
+
+	addProvidedFacts(gatheredFacts, values, provider)
+	logger.Log("debug", "facts provided", "facts", len(values))
+}
+
+// addProvidedFacts adds the fact values output by the provider to
+// the gathered facts, unless already gathered or not selected by SkipFacts,
+// OnlyFacts and Tags. Provided facts have no tags. Each skipped fact is
+// logged.
+func addProvidedFacts(gatheredFacts Facts, values map[string]string,
+	provider string) {
+	for name, value := range values {
+		if _, exists := gatheredFacts[name]; exists {
+			continue
+		}
+		if !selected(name, OnlyFacts, SkipFacts) || !tagged(nil) {
+			system.Log("info", "skipping fact", "name", name)
+			continue
+		}
+		gatheredFacts[name] = Fact{Name: name,
+			Result: system.Command{Command: provider, Stdout: value}}
+	}
+}
+
+// parseProviderOutput parses the output of the fact provider: a JSON object
+// mapping the fact names to their values, or KEY=VALUE lines in the format
+// of environment files. Strings are used as is, other JSON values in their
+// JSON encoding. Fact names must be valid environment variable names.
+func parseProviderOutput(output string) (map[string]string, error) {
+	if !strings.HasPrefix(strings.TrimSpace(output), "{") {
+		return parseEnvFile(output)
+	}
+	data, err := decodeJSON(output)
+	if err != nil {
+		return nil, err
+	}
+	object, _ := data.(map[string]interface{})
+	values := map[string]string{}
+	for name, value := range object {
+		if !identifierPattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid fact name %q", name)
+		}
+		values[name] = providerValue(value)
+	}
+	return values, nil
+}
+
+// providerValue returns the string of a JSON value: strings as is, other
+// values in their JSON encoding, e.g. "true" or "[1,2]".
+func providerValue(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
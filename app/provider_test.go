@@ -0,0 +1,171 @@
+package app
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseProviderOutput tests parsing the fact provider output as
+// a JSON object or as KEY=VALUE lines.
+func TestParseProviderOutput(t *testing.T) {
+	for _, test := range []struct {
+		Output   string
+		Expected map[string]string
+		Invalid  bool
+	}{
+		{Output: "region=eu-west-1\n# comment\nzone=\"a\"\n",
+			Expected: map[string]string{"region": "eu-west-1", "zone": "a"}},
+		{Output: ` {"region": "eu-west-1", "cpus": 4, "spot": true,
+			"tags": ["a", "b"], "none": null}`,
+			Expected: map[string]string{"region": "eu-west-1", "cpus": "4",
+				"spot": "true", "tags": `["a","b"]`, "none": "null"}},
+		{Output: "", Expected: map[string]string{}},
+		{Output: `{"region": `, Invalid: true},
+		{Output: `{"not-valid": "a"}`, Invalid: true},
+		{Output: "no value", Invalid: true},
+	} {
+		// when: We parse the output
+		values, err := parseProviderOutput(test.Output)
+
+		// then: We check the parsed values
+		assert.Equal(t, test.Invalid, err != nil, test.Output)
+		if !test.Invalid {
+			assert.Equal(t, test.Expected, values, test.Output)
+		}
+	}
+}
+
+// TestGatherConfigFactsProvider tests adding the facts output by the fact
+// provider to the facts defined in the configuration.
+//
+// It verifies that the provider does not replace defined facts, and that
+// a failing provider adds no facts.
+func TestGatherConfigFactsProvider(t *testing.T) {
+	config := Config{
+		Facts:    []Fact{{Name: "region", Command: "echo defined"}},
+		Provider: `echo '{"region": "provided", "zone": "a"}'`,
+	}
+
+	// when: We gather the facts
+	facts := gatherConfigFacts(context.Background(), config)
+
+	// then: We check the defined and the provided facts
+	assert.Equal(t, "defined", facts["region"].Result.Stdout)
+	assert.Equal(t, "a", facts["zone"].Result.Stdout)
+	assert.Equal(t, config.Provider, facts["zone"].Result.Command)
+	assert.Equal(t, "a", facts.toEnvironment()["zone"])
+
+	for _, provider := range []string{"echo zone=a; exit 1", "echo {"} {
+		// when: We gather the facts with a failing provider
+		config.Provider = provider
+		facts = gatherConfigFacts(context.Background(), config)
+
+		// then: We check that only the defined facts are gathered
+		assert.Len(t, facts, 1, provider)
+	}
+}
+
+// TestGatherConfigFactsProviderFiltered tests filtering the facts output
+// by the fact provider.
+//
+// It verifies that provided facts are skipped by SkipFacts, OnlyFacts and
+// Tags like facts without tags.
+func TestGatherConfigFactsProviderFiltered(t *testing.T) {
+	defer func() {
+		SkipFacts, OnlyFacts, Tags = nil, nil, nil
+	}()
+	config := Config{Provider: "echo region=eu; echo zone=a"}
+
+	for _, test := range []struct {
+		SkipFacts []string
+		OnlyFacts []string
+		Tags      []string
+		Expected  []string
+	}{
+		{Expected: []string{"region", "zone"}},
+		{SkipFacts: []string{"zone"}, Expected: []string{"region"}},
+		{OnlyFacts: []string{"zone"}, Expected: []string{"zone"}},
+		{Tags: []string{"deploy"}, Expected: []string{}},
+		{Tags: []string{UntaggedTag}, Expected: []string{"region", "zone"}},
+	} {
+		// given: We set the filters
+		SkipFacts, OnlyFacts, Tags = test.SkipFacts, test.OnlyFacts,
+			test.Tags
+
+		// when: We gather the facts
+		facts := gatherConfigFacts(context.Background(), config)
+
+		// then: We check that only the selected facts were provided
+		assert.ElementsMatch(t, test.Expected, slices.Collect(maps.Keys(facts)))
+	}
+}
+
+// TestGatherConfigFactsProviderDefaults tests executing the fact provider
+// with the default command settings.
+//
+// It verifies that the provider uses the default shell, directory and
+// environment, and is killed by the default timeout.
+func TestGatherConfigFactsProviderDefaults(t *testing.T) {
+	dir := t.TempDir()
+	config := Config{
+		Defaults: Defaults{Shell: "/bin/bash", Directory: dir,
+			Environment: map[string]string{"REGION": "eu"}},
+		Provider: `echo "shell=${BASH_VERSION:+bash}"; ` +
+			`echo "dir=$PWD"; echo "region=$REGION"`,
+	}
+
+	// when: We gather the facts
+	facts := gatherConfigFacts(context.Background(), config)
+
+	// then: We check that the defaults were used
+	assert.Equal(t, "bash", facts["shell"].Result.Stdout)
+	assert.Equal(t, dir, facts["dir"].Result.Stdout)
+	assert.Equal(t, "eu", facts["region"].Result.Stdout)
+
+	// when: We gather the facts with a provider exceeding the timeout
+	config.Defaults.Timeout = "1s"
+	config.Provider = "sleep 3; echo region=eu"
+	start := time.Now()
+	facts = gatherConfigFacts(context.Background(), config)
+
+	// then: We check that the provider was killed
+	assert.Empty(t, facts)
+	assert.Less(t, time.Since(start), 3*time.Second)
+}
+
+// TestRunProviderDefaults tests the fact provider of a configuration file
+// executed by Run.
+//
+// It verifies that the defaults of the configuration file are used by
+// the provider after merging the configuration.
+func TestRunProviderDefaults(t *testing.T) {
+	// given: We define a configuration file with a provider using
+	// the default environment
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	output := filepath.Join(dir, "output")
+	assert.Nil(t, os.WriteFile(file, []byte(`defaults:
+  environment:
+    GREETING: hello
+provider: echo "FROMPROV=$GREETING"
+actions:
+  - command: echo "$FROMPROV" > `+output+`
+`), 0600))
+
+	// when: We run the application
+	_, err := Run(context.Background(), file,
+		Config{Logging: system.LogConfig{File: "testing_buffer"}})
+
+	// then: We check that the provided fact used the default environment
+	assert.Nil(t, err)
+	content, _ := os.ReadFile(output)
+	assert.Equal(t, "hello\n", string(content))
+}
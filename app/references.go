@@ -0,0 +1,113 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// StrictValidation turns references to undefined facts in action rules
+// into validation errors. By default such references are only logged
+// as warnings, since they may refer to real environment variables.
+var StrictValidation bool
+
+// referencePattern matches ${NAME} and $NAME variable references.
+var referencePattern = regexp.MustCompile(`\$\{?([A-Za-z_]\w*)`)
+
+// factReference represents a variable referenced in an action rule.
+type factReference struct {
+	Action string // name of the action containing the rule
+	Rule   string // rule containing the reference
+	Name   string // referenced variable name
+}
+
+// undefinedReferences returns references in action rules to variables
+// which are neither defined facts nor environment variables of the process,
+// the action rules or the defaults. Facts output
+// by the fact provider are not known before the run, so no references are
+// returned if the provider is set.
+func undefinedReferences(config Config) []factReference {
+	references := []factReference{}
+	if config.Provider != "" {
+		return references
+	}
+	for _, action := range config.Actions {
+		environment := defaultEnvironment(action.ruleEnvironment(),
+			config.Defaults.Environment)
+		for _, rule := range action.Rules {
+			for _, reference := range undefinedRuleReferences(config.Facts,
+				rule, environment) {
+				reference.Action = action.name()
+				references = append(references, reference)
+			}
+		}
+	}
+	return references
+}
+
+// undefinedRuleReferences returns references in the rule to variables
+// which are neither defined facts nor variables of the process environment
+// or the rule environment.
+func undefinedRuleReferences(facts []Fact, rule string,
+	environment map[string]string) []factReference {
+	references := []factReference{}
+	for _, match := range referencePattern.FindAllStringSubmatch(rule, -1) {
+		name := match[1]
+		if variableDefined(name, environment) {
+			continue
+		}
+		if !factDefined(facts, name) {
+			references = append(references,
+				factReference{Rule: rule, Name: name})
+		}
+	}
+	return references
+}
+
+// variableDefined reports whether the variable is set in the process
+// environment or in the environment.
+func variableDefined(name string, environment map[string]string) bool {
+	if _, exists := environment[name]; exists {
+		return true
+	}
+	_, exists := os.LookupEnv(name)
+	return exists
+}
+
+// validateReferences returns an error if any action rule references
+// an undefined fact.
+func validateReferences(config Config) error {
+	references := undefinedReferences(config)
+	if len(references) == 0 {
+		return nil
+	}
+	return fmt.Errorf("rule %q references undefined fact %q",
+		references[0].Rule, references[0].Name)
+}
+
+// logUndefinedReferences logs a warning for each action rule referencing
+// an undefined fact, followed by a warning for each action with such
+// rules, since typos in fact names usually make the action unreachable.
+func logUndefinedReferences(config Config) {
+	references := undefinedReferences(config)
+	for _, reference := range references {
+		system.Log("warn", "rule references undefined fact", "rule",
+			reference.Rule, "name", reference.Name)
+	}
+	actions := []string{}
+	facts := map[string][]string{}
+	for _, reference := range references {
+		if _, exists := facts[reference.Action]; !exists {
+			actions = append(actions, reference.Action)
+		}
+		facts[reference.Action] = append(facts[reference.Action],
+			reference.Name)
+	}
+	for _, action := range actions {
+		system.Log("warn", "action may be unreachable", "action", action,
+			"undefined", strings.Join(facts[action], ","))
+	}
+}
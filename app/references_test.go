@@ -0,0 +1,171 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/stretchr/testify/assert"
+)
+
+// referencesConfig is a configuration with a rule referencing
+// an undefined fact.
+var referencesConfig = Config{
+	Facts: []Fact{
+		{Name: "load", Command: "echo 16"},
+		{Name: "instance", Command: "cat instance.json", Parse: "json"},
+	},
+	Actions: []Action{
+		{
+			Command: "echo roundish-denial-cupcake",
+			Rules: []string{
+				"[[ ${load} -gt 15 ]]",
+				"[[ $instance_state == running ]]",
+				"[[ ${typo_fact:-0} -eq 0 && -n ${HOME} ]]",
+			},
+		},
+	},
+}
+
+// TestUndefinedReferences tests the undefinedReferences function.
+//
+// It verifies that only references to names which are neither facts,
+// values parsed from facts nor environment variables are returned, and
+// none if facts are output by the fact provider.
+func TestUndefinedReferences(t *testing.T) {
+	t.Setenv("HOME", "/root")
+
+	expected := []factReference{
+		{Action: "echo roundish-denial-cupcake",
+			Rule: "[[ ${typo_fact:-0} -eq 0 && -n ${HOME} ]]", Name: "typo_fact"},
+	}
+	assert.Equal(t, expected, undefinedReferences(referencesConfig))
+
+	// then: We check that no references are undefined with a provider
+	provided := referencesConfig
+	provided.Provider = "echo typo_fact=1"
+	assert.Empty(t, undefinedReferences(provided))
+}
+
+// TestUndefinedReferencesEnvironment tests the undefinedReferences function
+// with rules referencing variables of the rule environment.
+//
+// It verifies that variables of the action environment, the rule
+// environment and the default environment are defined, so strict
+// validation accepts them.
+func TestUndefinedReferencesEnvironment(t *testing.T) {
+	StrictValidation = true
+	defer func() {
+		StrictValidation = false
+	}()
+	threshold := map[string]string{"THRESHOLD": "5"}
+	for _, config := range []Config{
+		{Actions: []Action{{Environment: threshold}}},
+		{Actions: []Action{{RuleEnvironment: threshold}}},
+		{Actions: []Action{{}}, Defaults: Defaults{Environment: threshold}},
+	} {
+		// given: An action with a rule referencing the variable
+		config.Facts = []Fact{{Name: "load", Command: "echo 7"}}
+		config.Actions[0].Command = "echo threshold"
+		config.Actions[0].Rules = []string{"test $load -gt $THRESHOLD"}
+
+		// when: We look for undefined references
+		references := undefinedReferences(config)
+
+		// then: We check that the variable is defined, also in strict mode
+		assert.Empty(t, references, config)
+		assert.Nil(t, validateConfig(config), config)
+	}
+}
+
+// TestUndefinedReferencesProbes tests the undefinedReferences function
+// with rules referencing values of HTTP probes and stat facts.
+//
+// It verifies that the values exported by the facts are defined, while
+// values of other facts are not.
+func TestUndefinedReferencesProbes(t *testing.T) {
+	// given: Facts exporting values and a rule referencing them
+	config := Config{
+		Facts: []Fact{
+			{Name: "api", HTTP: &HTTPProbe{URL: "http://localhost/"}},
+			{Name: "pid", Stat: "/var/run/app.pid"},
+			{Name: "load", Command: "echo 7"},
+		},
+		Actions: []Action{{Command: "echo restart", Rules: []string{
+			"[[ $api_status -eq 503 && $pid_age -gt 60 && $load_x ]]",
+		}}},
+	}
+
+	// when: We look for undefined references
+	references := undefinedReferences(config)
+
+	// then: We check that only the value of the command fact is undefined
+	assert.Equal(t, []factReference{{Action: "echo restart",
+		Rule: config.Actions[0].Rules[0], Name: "load_x"}}, references)
+}
+
+// TestValidateReferences tests the validation of rule references with and
+// without strict validation.
+//
+// In default mode the undefined references are only logged as warnings,
+// in strict mode validation fails.
+func TestValidateReferences(t *testing.T) {
+	defer func() {
+		StrictValidation = false
+	}()
+
+	// given: We define the logging settings
+	_ = system.LogInit(system.LogConfig{
+		File:  "testing_buffer",
+		Level: "debug",
+		Quiet: false,
+		JSON:  false,
+	})
+
+	// then: We check that validation passes in default mode
+	StrictValidation = false
+	assert.Nil(t, validateConfig(referencesConfig))
+
+	// then: We check that the undefined reference and the action are logged
+	logUndefinedReferences(referencesConfig)
+	assert.Regexp(t, "level=WARN msg=\"rule references undefined fact\" "+
+		"rule=.+ name=typo_fact\n.+level=WARN "+
+		"msg=\"action may be unreachable\" "+
+		"action=\"echo roundish-denial-cupcake\" undefined=typo_fact\n$",
+		system.GetTestingStdout())
+
+	// then: We check that validation fails in strict mode
+	StrictValidation = true
+	assert.EqualError(t, validateConfig(referencesConfig),
+		"rule \"[[ ${typo_fact:-0} -eq 0 && -n ${HOME} ]]\" "+
+			"references undefined fact \"typo_fact\"")
+}
+
+// TestLogUnreachableActions tests the warnings about actions whose rules
+// reference undefined facts.
+//
+// It verifies that one warning is logged per action listing all its
+// undefined facts, and that actions without such rules are not logged.
+func TestLogUnreachableActions(t *testing.T) {
+	config := Config{
+		Facts: []Fact{{Name: "apacheIsRunning", Command: "echo 0"}},
+		Actions: []Action{
+			{Name: "stop", Command: "echo stop", Rules: []string{
+				"[[ ${apacheIsRuning} -eq 0 ]]", "[[ ${loadAvg} -gt 15 ]]"}},
+			{Name: "start", Command: "echo start",
+				Rules: []string{"[[ ${apacheIsRunning} -ne 0 ]]"}},
+		},
+	}
+	_ = system.LogInit(system.LogConfig{
+		File:  "testing_buffer",
+		Level: "warn",
+	})
+
+	// when: We log the undefined references
+	logUndefinedReferences(config)
+
+	// then: We check the logged unreachable actions
+	output := system.GetTestingStdout()
+	assert.Contains(t, output, "msg=\"action may be unreachable\" "+
+		"action=stop undefined=apacheIsRuning,loadAvg\n")
+	assert.NotContains(t, output, "action=start")
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// ReloadDebounce is the time the configuration files must stay unmodified
+// before they are reloaded in daemon mode, so a file saved in several
+// writes, e.g. by an editor, is reloaded once, complete. Disabled if zero.
+var ReloadDebounce = 500 * time.Millisecond
+
+// waitConfigSettled waits until the configuration file and ConfigDir have
+// not been modified for ReloadDebounce, or the context is done. It only
+// waits when reloading the configuration in daemon mode.
+func waitConfigSettled(ctx context.Context, file string) {
+	if !DaemonMode || !applicationStarted {
+		return
+	}
+	for {
+		wait := ReloadDebounce - configAge(file)
+		if wait <= 0 {
+			return
+		}
+		system.Log("debug", "configuration modified, delaying reload", "ms",
+			wait.Milliseconds())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// configAge returns the time since the last modification of
+// the configuration file, ConfigDir or its files, at most ReloadDebounce.
+// Files read from the standard input or a URL, files which cannot be
+// accessed and modification times in the future are ignored.
+func configAge(file string) time.Duration {
+	age := ReloadDebounce
+	for _, path := range configPaths(file) {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if modified := mockNow().Sub(info.ModTime()); modified >= 0 {
+			age = min(age, modified)
+		}
+	}
+	return age
+}
+
+// configPaths returns the paths of the configuration file unless read from
+// the standard input or a URL, ConfigDir and its configuration files.
+func configPaths(file string) []string {
+	paths := []string{}
+	if file != "" && file != "-" && !isURL(file) {
+		paths = append(paths, file)
+	}
+	if ConfigDir != "" {
+		files, _ := configDirFiles(ConfigDir)
+		paths = append(append(paths, ConfigDir), files...)
+	}
+	return paths
+}
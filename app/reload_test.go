@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestConfigAge tests the time since the last modification of
+// the configuration files.
+//
+// It verifies that the most recent modification of the file, the directory
+// and its files counts, limited to ReloadDebounce, and that the standard
+// input and modification times in the future are ignored.
+func TestConfigAge(t *testing.T) {
+	defer func() {
+		ConfigDir = ""
+		mockNow = time.Now
+	}()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	fragment := filepath.Join(dir, "conf.d", "extra.yaml")
+	assert.Nil(t, os.Mkdir(filepath.Dir(fragment), 0o755))
+	for _, path := range []string{file, fragment} {
+		assert.Nil(t, os.WriteFile(path, []byte("actions: []\n"), 0o600))
+	}
+	now := time.Now()
+	assert.Nil(t, os.Chtimes(file, now, now.Add(-200*time.Millisecond)))
+	assert.Nil(t, os.Chtimes(filepath.Dir(fragment), now, now.Add(-time.Hour)))
+	assert.Nil(t, os.Chtimes(fragment, now, now.Add(-100*time.Millisecond)))
+	mockNow = func() time.Time { return now }
+
+	// then: We check the age of the configuration file
+	assert.Equal(t, 200*time.Millisecond, configAge(file))
+	assert.Equal(t, ReloadDebounce, configAge("-"))
+
+	// then: We check the age including the configuration directory
+	ConfigDir = filepath.Dir(fragment)
+	assert.Equal(t, 100*time.Millisecond, configAge(file))
+
+	// then: We check that modification times in the future are ignored
+	mockNow = func() time.Time { return now.Add(-time.Minute) }
+	assert.Equal(t, ReloadDebounce, configAge(file))
+}
+
+// TestWaitConfigSettled tests delaying the reload of a modified
+// configuration file.
+//
+// It verifies that the reload waits until the file is unmodified for
+// ReloadDebounce in daemon mode only.
+func TestWaitConfigSettled(t *testing.T) {
+	defer func() {
+		DaemonMode = false
+		ReloadDebounce = 500 * time.Millisecond
+	}()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte("actions: []\n"), 0o600))
+	ReloadDebounce = 200 * time.Millisecond
+
+	for _, test := range []struct {
+		DaemonMode bool
+		Started    bool
+		Waits      bool
+	}{
+		{DaemonMode: false, Started: true, Waits: false},
+		{DaemonMode: true, Started: false, Waits: false},
+		{DaemonMode: true, Started: true, Waits: true},
+	} {
+		DaemonMode = test.DaemonMode
+		started := applicationStarted
+		applicationStarted = test.Started
+		assert.Nil(t, os.Chtimes(file, time.Now(), time.Now()))
+
+		// when: We wait for the configuration file
+		startTime := time.Now()
+		waitConfigSettled(context.Background(), file)
+		elapsed := time.Since(startTime)
+		applicationStarted = started
+
+		// then: We check whether the reload was delayed
+		assert.Equal(t, test.Waits, elapsed >= 150*time.Millisecond, test)
+	}
+}
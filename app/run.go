@@ -1,57 +1,109 @@
 package app
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/piotr-ku/yaml-runner-go/system"
 )
 
 var applicationStarted bool
 var configurationHash uint32
+var factsHash uint32
+var lastRunFailed bool
+
+// runMutex serializes the runs of Run and Execute, so runs triggered close
+// together never execute the same actions concurrently and share the state
+// of the previous run, e.g. factsHash.
+var runMutex sync.Mutex
+
+// DaemonMode enables validation of settings required to run the application
+// periodically, e.g. the daemon interval.
+var DaemonMode bool
+
+// ForceRun disables skipping actions in daemon mode when the gathered facts
+// are unchanged since the previous run.
+var ForceRun bool
+
+// CacheWithinRun enables reusing the results of identical commands, e.g.
+// the same command of a fact and a rule, within a single run.
+var CacheWithinRun bool
+
+// ResultsHook is called with the results of every run of Run unless nil,
+// e.g. to collect the fact values and the action return codes in-process
+// instead of parsing the log messages.
+var ResultsHook func(Results)
 
 // Run executes all the actions defined in the configuration file.
 // It loads the configuration from the specified file and merges it with
-// the provided merge configuration.
+// the YRG_* environment variables and the provided merge configuration.
 // It initializes logging and gathers facts before executing the actions.
+// Concurrent calls are serialized, a call waits for the running one to
+// finish.
+// Facts and actions are filtered by SkipFacts, SkipActions, OnlyFacts and
+// OnlyActions.
 //
 // Parameters:
+//   - ctx: The parent context; cancelling it aborts running commands.
 //   - configFile: The path to the configuration file.
 //   - configArgs: The merge configuration to combine with the loaded
 //     configuration.
-func Run(configFile string, configArgs Config) Config {
+//
+// It returns the merged configuration and an error if the configuration
+// cannot be loaded or logging cannot be initialized.
+func Run(ctx context.Context, configFile string,
+	configArgs Config) (Config, error) {
+	// Wait for the running run to finish
+	runMutex.Lock()
+	defer runMutex.Unlock()
+
 	// Default settings
-	config := Config{
-		// Default daemon settings
-		Daemon: Daemon{
-			Interval: "2s",
-		},
-		// Default logging settings
-		Logging: system.LogConfig{
-			File:  "",
-			Quiet: false,
-			JSON:  false,
-			Level: "info",
-		},
-	}
+	config := defaultConfig()
+
+	// Wait until the modified configuration files are saved completely
+	waitConfigSettled(ctx, configFile)
 
-	// Load configuration file
-	contentFile := LoadConfigFile(configFile)
+	// Load configuration file and fragments
+	contentFile, err := LoadConfig(configFile)
+	if err != nil {
+		return config, err
+	}
 	config.Merge(contentFile)
 
+	// Load configuration from environment variables
+	config.Merge(LoadConfigEnvironment())
+
 	// Load configuration from arguments
 	config.Merge(configArgs)
 
 	// Calculate configuration hash
 	config.CalculateHash()
 
-	// Initialize logging
-	system.LogInit(system.LogConfig{
-		File:  config.Logging.File,
-		Quiet: config.Logging.Quiet,
-		JSON:  config.Logging.JSON,
-		Level: config.Logging.Level,
-	})
+	// Initialize logging, truncating log files only once, so the daemon
+	// keeps appending
+	logConfig := config.Logging
+	logConfig.Truncate = config.Logging.Truncate && !applicationStarted
+	err = system.LogInit(logConfig)
+	if err != nil {
+		return config, err
+	}
+	// Correlate the log messages of the run
+	system.Bind("run_id", newRunID())
+
+	// Validate daemon interval unless runs are scheduled by cron
+	if DaemonMode && config.Daemon.Cron == "" {
+		if err := config.Daemon.ValidateInterval(); err != nil {
+			return config, system.WrapError(&ValidationError{Err: err})
+		}
+	}
 
 	// Log application startup
 	if !applicationStarted {
@@ -63,21 +115,261 @@ func Run(configFile string, configArgs Config) Config {
 	if config.Hash != configurationHash {
 		// Update configuration hash
 		configurationHash = config.Hash
+		// Run actions after reloading even if the facts are unchanged
+		factsHash = 0
 
 		// Log configuration changes
 		system.Log("debug", "configuration hash", "hash", configurationHash)
 		system.Log("info", "configuration loaded", "file", configFile, "facts",
 			len(config.Facts), "actions", len(config.Actions))
 		system.Log("debug", "configuration dump", "config", config)
+
+		// Warn about rules referencing undefined facts
+		logUndefinedReferences(config)
+	}
+
+	// Count the run
+	metrics.observeRun()
+
+	// Gather facts and execute actions except the skipped ones
+	results, err := run(ctx, config.Filtered())
+	lastRunFailed = runFailed(results, err)
+	if ResultsHook != nil {
+		ResultsHook(results)
+	}
+
+	// Return configuration
+	return config, err
+}
+
+// defaultConfig returns the default settings the configuration file,
+// the environment variables and the merge configuration are merged into.
+func defaultConfig() Config {
+	return Config{
+		// Default daemon settings
+		Daemon: Daemon{
+			Interval: "2s",
+		},
+		// Default logging settings
+		Logging: system.LogConfig{
+			File:  "",
+			Quiet: false,
+			JSON:  false,
+			Level: "info",
+		},
+	}
+}
+
+// Results provides the results of a run: the gathered facts and
+// the results of the actions.
+type Results struct {
+	Facts    Facts          // gathered facts
+	Actions  []ActionResult // results of the actions in configuration order
+	Duration time.Duration  // duration of gathering facts and actions
+	Skipped  bool           // whether the actions were skipped
+}
+
+// logSummary logs the numbers of evaluated, executed and failed actions,
+// so every run logs an info message, also if no action rules passed.
+func (r Results) logSummary() {
+	summary := r.Summary()
+	system.Log("info", "run completed", "evaluated", summary.Evaluated,
+		"executed", summary.Executed, "failed", summary.Failed)
+}
+
+// failed reports whether actions were executed and all of them failed.
+func (r Results) failed() bool {
+	executed := false
+	for _, action := range r.Actions {
+		if action.Executed && !action.Failed() {
+			return false
+		}
+		executed = executed || action.Executed
+	}
+	return executed
+}
+
+// anyFailed reports whether an executed action failed.
+func (r Results) anyFailed() bool {
+	return slices.ContainsFunc(r.Actions, ActionResult.Failed)
+}
+
+// runFailed reports whether the run failed: it returned an error, or
+// actions were executed and all of them failed. A run skipping the actions
+// as the facts are unchanged keeps the state of the previous run, so
+// the daemon keeps backing off from a failing target.
+func runFailed(results Results, err error) bool {
+	if err == nil && results.Skipped {
+		return lastRunFailed
+	}
+	return err != nil || results.failed()
+}
+
+// LastRunFailed reports whether the last run of Run failed: it returned
+// an error, or actions were executed and all of them failed. Runs which
+// executed no actions are successful, except runs skipping the actions as
+// the facts are unchanged, which keep the state of the previous run.
+func LastRunFailed() bool {
+	return lastRunFailed
+}
+
+// newRunID returns a random UUID (version 4) identifying a run.
+func newRunID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40 // version 4
+	id[8] = id[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10],
+		id[10:])
+}
+
+// Execute applies the defaults block, normalizes the log levels, validates
+// the configuration, gathers facts and executes actions. It is
+// the entrypoint for embedding the application in Go programs: errors are
+// returned and never terminate the process. The before and after hooks and
+// the run timeout are applied as in Run. Log messages are discarded unless
+// logging is initialized with system.LogInit. It returns a ValidationError
+// if the configuration is invalid or a shell is missing and an OSError if
+// the before hook fails. Concurrent calls of Execute and Run are executed
+// one after another.
+func Execute(ctx context.Context, config Config) (Results, error) {
+	runMutex.Lock()
+	defer runMutex.Unlock()
+	config.applyDefaults()
+	config.normalizeLevels()
+	if err := mockValidateConfig(config); err != nil {
+		return Results{}, system.WrapError(&ValidationError{Err: err})
+	}
+	return run(ctx, config)
+}
+
+// run runs the hooks, gathers facts and executes actions of a validated
+// configuration. In daemon mode actions are skipped if the facts are
+// unchanged since the previous run. It returns a ValidationError if
+// a shell used by the configuration is missing and an OSError if
+// the before hook fails.
+func run(ctx context.Context, config Config) (Results, error) {
+	// Check shells before running any command
+	if err := checkShells(config); err != nil {
+		return Results{}, err
+	}
+
+	// Run the after hook even if the run fails or is cancelled
+	defer func() {
+		_ = runHook(context.WithoutCancel(ctx), "after", config.After,
+			config.WorkDir)
+	}()
+
+	// Set run timeout
+	ctx, cancel := runContext(ctx, config.Daemon.RunTimeout)
+	defer cancel()
+
+	// Run the before hook, a failure aborts the run
+	err := runHook(ctx, "before", config.Before, config.WorkDir)
+	if err != nil {
+		return Results{}, system.WrapError(&OSError{Err: err})
 	}
 
 	// Gather facts
-	facts := gatherFacts(config.Facts)
-	system.Log("debug", "facts", "facts", facts)
+	startTime := time.Now()
+	results := Results{Facts: gatherConfigFacts(ctx, config)}
+	system.Log("debug", "facts", "facts", results.Facts)
+
+	// Skip actions in daemon mode if the facts are unchanged
+	if factsUnchanged(results.Facts) && DaemonMode && !ForceRun {
+		system.Log("info", "facts unchanged, skipping actions")
+		results.Skipped = true
+		results.Duration = time.Since(startTime)
+		results.logSummary()
+		return results, nil
+	}
 
 	// Execute actions
-	executeActions(config.Actions, facts)
+	results.Actions = executeActions(ctx, config.Actions, results.Facts)
+	results.Duration = time.Since(startTime)
 
-	// Return configuration
-	return config
+	// Log run timeout or cancellation
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		system.Log("error", "run timed out", "timeout",
+			config.Daemon.RunTimeout)
+	case errors.Is(ctx.Err(), context.Canceled):
+		system.Log("warn", "run cancelled")
+	}
+
+	// Notify about failed actions
+	notifyFailures(ctx, config.NotifyURL, results.Actions)
+	// Retry failed or not reached actions in the next run
+	saveFactsHash(results, ctx.Err() == nil)
+	results.logSummary()
+	return results, nil
+}
+
+// factsUnchanged reports whether facts are gathered and their hash equals
+// the hash saved by the previous run. Without facts the actions cannot be
+// skipped, since nothing tells whether their rules would pass again.
+func factsUnchanged(facts Facts) bool {
+	return len(facts) > 0 && hashFacts(facts) == factsHash
+}
+
+// saveFactsHash saves the hash of the facts of the run, so the next run
+// gathering the same facts skips the actions. The hash is cleared if
+// the run is not complete or an executed action failed, so the actions
+// are retried in the next run.
+func saveFactsHash(results Results, complete bool) {
+	factsHash = 0
+	if complete && !results.anyFailed() {
+		factsHash = hashFacts(results.Facts)
+	}
+}
+
+// hashFacts returns the hash of the fact values.
+func hashFacts(facts Facts) uint32 {
+	data, _ := json.Marshal(facts.toEnvironment())
+	hash, _ := adler32Hash(data)
+	return hash
+}
+
+// runContext returns a context for a single run derived from the parent
+// context. If the timeout is set, the context is cancelled when the timeout
+// is exceeded. If CacheWithinRun is set, the context memoizes the results
+// of identical commands.
+func runContext(parent context.Context,
+	timeout string) (context.Context, context.CancelFunc) {
+	ctx := parent
+	if CacheWithinRun {
+		ctx = system.WithCommandCache(parent)
+	}
+	duration, err := parseDuration(timeout)
+	if err != nil || duration <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, duration)
+}
+
+// GatherFacts gathers the facts defined in the configuration without
+// executing actions. Facts are filtered by SkipFacts and OnlyFacts.
+// The shells check, the before and after hooks and the run timeout are
+// applied as in Run. It returns a ValidationError if a shell is missing
+// and an OSError if the before hook fails.
+func GatherFacts(ctx context.Context, config Config) (Facts, error) {
+	if err := checkShells(config); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = runHook(context.WithoutCancel(ctx), "after", config.After,
+			config.WorkDir)
+	}()
+
+	ctx, cancel := runContext(ctx, config.Daemon.RunTimeout)
+	defer cancel()
+
+	err := runHook(ctx, "before", config.Before, config.WorkDir)
+	if err != nil {
+		return nil, system.WrapError(&OSError{Err: err})
+	}
+	selected := config
+	selected.Facts = filterFacts(config.Facts)
+	return gatherConfigFacts(ctx, selected), nil
 }
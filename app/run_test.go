@@ -1,13 +1,27 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/piotr-ku/yaml-runner-go/system"
 	"github.com/stretchr/testify/assert"
 )
 
-const emptyConfigHash = 0xe8b4543d
+// runIDPattern matches the run ID added to the log messages of a run.
+const runIDPattern = "run_id=[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-" +
+	"[89ab][0-9a-f]{3}-[0-9a-f]{12}"
+
+const emptyConfigHash = 0xca617649
 
 // TestRunEmptyConfig tests the Run function with an empty configuration.
 //
@@ -16,6 +30,9 @@ const emptyConfigHash = 0xe8b4543d
 // that the result of calling the Run function with a testing
 // configuration file and an empty Config struct matches the expected value.
 func TestRunEmptyConfig(t *testing.T) {
+	// the testing configuration uses /bin/zsh which may not be installed
+	mockInstalledShells(t)
+
 	expect := Config{
 		Daemon: Daemon{
 			Interval: "5s",
@@ -102,5 +119,725 @@ func TestRunEmptyConfig(t *testing.T) {
 		Hash: emptyConfigHash,
 	}
 
-	assert.Equal(t, expect, Run(testingConfigFile, Config{}))
+	config, err := Run(context.Background(), testingConfigFile, Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, expect, config)
+}
+
+// TestDefaultConfig tests the default settings of Run.
+//
+// It verifies that the default configuration is valid apart from
+// the actions and that it logs in text format at info level.
+func TestDefaultConfig(t *testing.T) {
+	// when: We construct the default configuration
+	config := defaultConfig()
+
+	// then: We check the default settings
+	assert.Equal(t, "2s", config.Daemon.Interval)
+	assert.Nil(t, config.Daemon.ValidateInterval())
+	assert.Equal(t, system.LogConfig{Level: "info"}, config.Logging)
+	config.Actions = []Action{{Command: "true"}}
+	assert.Nil(t, config.Validate())
+}
+
+// TestRunTimeout tests the Run function with a run timeout exceeded.
+//
+// It writes a temporary configuration file with a fact that takes longer
+// than the configured run timeout and verifies that the timeout was logged
+// and the actions were not executed.
+func TestRunTimeout(t *testing.T) {
+	// given: We define a configuration file with a short run timeout
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	content := []byte(`
+        daemon:
+          run_timeout: 100ms
+        facts:
+          - name: slowFact
+            command: sleep 3
+        actions:
+          - command: echo hidden-clamp-waltz
+    `)
+	assert.Nil(t, os.WriteFile(file, content, 0600))
+
+	// when: We run the application
+	config, _ := Run(context.Background(), file, Config{
+		Logging: system.LogConfig{File: "testing_buffer", Level: "debug"},
+	})
+
+	// then: We check that the run timed out and no action was executed
+	assert.Equal(t, "100ms", config.Daemon.RunTimeout)
+	assert.Regexp(t, "level=ERROR msg=\"run timed out\" "+
+		runIDPattern+" timeout=100ms",
+		system.GetTestingStderr())
+	assert.NotContains(t, system.GetTestingStdout(), "action executed")
+}
+
+// TestRunCancelled tests the Run function with a cancelled parent context.
+//
+// It cancels the context before the run and verifies that the cancellation
+// was logged and no facts were gathered.
+func TestRunCancelled(t *testing.T) {
+	// given: We define a configuration file and a cancelled context
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	content := []byte(`
+        facts:
+          - name: cancelledFact
+            command: echo verbose-parka-dizzy
+        actions:
+          - command: echo flatly-sincere-mural
+    `)
+	assert.Nil(t, os.WriteFile(file, content, 0600))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// when: We run the application
+	_, _ = Run(ctx, file, Config{
+		Logging: system.LogConfig{File: "testing_buffer", Level: "debug"},
+	})
+
+	// then: We check that the run was cancelled
+	assert.Regexp(t, "level=WARN msg=\"run cancelled\"",
+		system.GetTestingStdout())
+	assert.NotContains(t, system.GetTestingStdout(), "fact gathered")
+}
+
+// TestRunDaemonModeInvalidInterval tests the Run function in daemon mode
+// with an interval shorter than the minimal one.
+//
+// It verifies that the run fails with a validation error.
+func TestRunDaemonModeInvalidInterval(t *testing.T) {
+	DaemonMode = true
+	defer func() {
+		DaemonMode = false
+	}()
+
+	// when: We run the application with a zero interval
+	_, err := Run(context.Background(), testingConfigFile, Config{
+		Daemon:  Daemon{Interval: "0s"},
+		Logging: system.LogConfig{File: "testing_buffer", Level: "debug"},
+	})
+
+	// then: We check that the run failed with a validation error
+	assertErrorName(t, "ValidationError", err)
+}
+
+// TestRunDaemonModeIntervalPrecedence tests resolving the daemon interval
+// in daemon mode.
+//
+// It verifies that the interval is validated after merging the sources,
+// so an invalid environment value overridden by the flag is accepted,
+// while an interval cleared by the flag is rejected.
+func TestRunDaemonModeIntervalPrecedence(t *testing.T) {
+	DaemonMode = true
+	defer func() {
+		DaemonMode = false
+	}()
+	t.Setenv("YRG_DAEMON_INTERVAL", "invalid")
+	logging := system.LogConfig{File: "testing_buffer", Level: "debug"}
+
+	// when: We override the invalid interval with the flag
+	flag := Config{Logging: logging}
+	flag.SetInterval("5s")
+	config, err := Run(context.Background(), testingConfigFile, flag)
+
+	// then: We check that the flag interval is used
+	assert.NotContains(t, fmt.Sprint(err), "interval")
+	assert.Equal(t, "5s", config.Daemon.Interval)
+
+	// when: We clear the interval with the flag
+	flag.SetInterval("")
+	_, err = Run(context.Background(), testingConfigFile, flag)
+
+	// then: We check that the run failed with a validation error
+	assertErrorName(t, "ValidationError", err)
+	assert.ErrorContains(t, err, "daemon interval or cron is not set")
+}
+
+// TestRunResultsHook tests capturing the results of Run with
+// the ResultsHook.
+//
+// It verifies that the hook receives the gathered facts and the results
+// of the executed actions.
+func TestRunResultsHook(t *testing.T) {
+	var captured []Results
+	ResultsHook = func(results Results) {
+		captured = append(captured, results)
+	}
+	defer func() {
+		ResultsHook = nil
+	}()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte(`facts:
+  - name: status
+    command: echo ok
+actions:
+  - command: echo failed; exit 3
+    rules:
+      - test "$status" = ok
+  - command: echo skipped
+    rules:
+      - "false"
+`), 0o600))
+
+	// when: We run the application
+	_, err := Run(context.Background(), file, Config{
+		Logging: system.LogConfig{File: "testing_buffer", Level: "debug"},
+	})
+
+	// then: We check the captured results
+	assert.Nil(t, err)
+	assert.Len(t, captured, 1)
+	results := captured[0]
+	assert.Equal(t, "ok", results.Facts["status"].Result.Stdout)
+	assert.True(t, results.Actions[0].Executed)
+	assert.Equal(t, 3, results.Actions[0].Result.Rc)
+	assert.Equal(t, "failed", results.Actions[0].Result.Stdout)
+	assert.NotNil(t, results.Actions[0].Result.Error)
+	assert.False(t, results.Actions[1].Executed)
+}
+
+// TestRunSerialized tests that concurrent runs do not overlap.
+//
+// It verifies that the actions of a run start only after the actions
+// of the running run finished.
+func TestRunSerialized(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte(`actions:
+  - command: echo start >> runs.log; sleep 0.2; echo end >> runs.log
+workdir: `+dir+`
+`), 0o600))
+
+	// when: We run the application twice concurrently
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Run(context.Background(), file, Config{
+				Logging: system.LogConfig{File: "testing_buffer"},
+			})
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// then: We check that the runs did not overlap
+	runs, err := os.ReadFile(filepath.Join(dir, "runs.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "start\nend\nstart\nend\n", string(runs))
+}
+
+// TestRunEnvironmentPrecedence tests the precedence of the configuration
+// sources in the Run function.
+//
+// Environment variables override the configuration file, arguments
+// override environment variables.
+func TestRunEnvironmentPrecedence(t *testing.T) {
+	// given: We define the environment variables
+	t.Setenv("YRG_DAEMON_INTERVAL", "10s")
+	t.Setenv("YRG_LOG_LEVEL", "warn")
+
+	// when: We run the application with the level argument
+	config, _ := Run(context.Background(), testingConfigFile, Config{
+		Logging: system.LogConfig{File: "testing_buffer", Level: "debug"},
+	})
+
+	// then: We check the precedence of the configuration sources
+	assert.Equal(t, "10s", config.Daemon.Interval)
+	assert.Equal(t, "debug", config.Logging.Level)
+}
+
+// TestRunLogFileModes tests the Run function with the log file and
+// directory permissions.
+//
+// It verifies that the permissions are applied to the created log file
+// and its parent directory.
+func TestRunLogFileModes(t *testing.T) {
+	// given: We define a log file in a missing directory
+	mockInstalledShells(t)
+	dir := filepath.Join(t.TempDir(), "log")
+	file := filepath.Join(dir, "runner.log")
+
+	// when: We run the application with the log permissions
+	_, err := Run(context.Background(), testingConfigFile, Config{
+		Logging: system.LogConfig{File: file, Quiet: true,
+			FileMode: "0640", DirMode: "0750"},
+	})
+
+	// then: We check the permissions of the log file and directory
+	assert.Nil(t, err)
+	info, err := os.Stat(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, fs.FileMode(0750), info.Mode().Perm())
+	info, err = os.Stat(file)
+	assert.Nil(t, err)
+	assert.Equal(t, fs.FileMode(0640), info.Mode().Perm())
+}
+
+// TestRunLogRunID tests the run ID added to the log messages by the Run
+// function.
+//
+// It verifies that all messages of a run share the run ID and that
+// subsequent runs get new IDs.
+func TestRunLogRunID(t *testing.T) {
+	// given: We define the logging settings enabling the text console output
+	mockInstalledShells(t)
+	overwrite := Config{
+		Logging: system.LogConfig{File: "testing_buffer", Level: "debug"},
+	}
+	overwrite.SetQuiet(false)
+	overwrite.SetJSON(false)
+
+	ids := []string{}
+	for range 2 {
+		// when: We run the application
+		_, _ = Run(context.Background(), testingConfigFile, overwrite)
+
+		// then: We check that all messages share the run ID
+		lines := strings.Split(strings.TrimSpace(system.GetTestingStdout()),
+			"\n")
+		id := regexp.MustCompile(runIDPattern).FindString(lines[0])
+		assert.NotEmpty(t, id)
+		for _, line := range lines {
+			assert.Contains(t, line, id)
+		}
+		ids = append(ids, id)
+	}
+
+	// then: We check that the runs got different IDs
+	assert.NotEqual(t, ids[0], ids[1])
+}
+
+// TestRunHooks tests the Run function with the before and after hooks.
+//
+// It verifies that the before hook runs before gathering facts and
+// the after hook runs after executing actions.
+func TestRunHooks(t *testing.T) {
+	// given: We define a configuration file with hooks
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	hooksLog := filepath.Join(dir, "hooks.log")
+	content := []byte(strings.ReplaceAll(`
+        before: echo before >> LOG
+        after: echo after >> LOG
+        facts:
+          - name: hookFact
+            command: echo fact >> LOG
+        actions:
+          - command: echo action >> LOG
+    `, "LOG", hooksLog))
+	assert.Nil(t, os.WriteFile(file, content, 0600))
+
+	// when: We run the application
+	_, err := Run(context.Background(), file, Config{
+		Logging: system.LogConfig{File: "testing_buffer", Level: "info"},
+	})
+
+	// then: We check the order of the executed commands
+	assert.Nil(t, err)
+	output, _ := os.ReadFile(hooksLog)
+	assert.Equal(t, "before\nfact\naction\nafter\n", string(output))
+	assert.Regexp(t, "level=INFO msg=\"hook executed\" "+
+		runIDPattern+" hook=before",
+		system.GetTestingStdout())
+	assert.Regexp(t, "level=INFO msg=\"hook executed\" "+
+		runIDPattern+" hook=after",
+		system.GetTestingStdout())
+}
+
+// TestRunBeforeHookFailure tests the Run function with a failing before
+// hook.
+//
+// It verifies that the run is aborted with an OSError, no facts are
+// gathered and the after hook still runs.
+func TestRunBeforeHookFailure(t *testing.T) {
+	// given: We define a configuration file with a failing before hook
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	content := []byte(`
+        before: exit 3
+        after: echo gallon-spoon-ramble
+        facts:
+          - name: skippedFact
+            command: echo skipped
+        actions:
+          - command: echo skipped
+    `)
+	assert.Nil(t, os.WriteFile(file, content, 0600))
+
+	// when: We run the application
+	_, err := Run(context.Background(), file, Config{
+		Logging: system.LogConfig{File: "testing_buffer", Level: "debug"},
+	})
+
+	// then: We check that the run was aborted and the after hook ran
+	assertErrorName(t, "OSError", err)
+	assert.ErrorContains(t, err, "before hook failed")
+	assert.Regexp(t, "level=ERROR msg=\"hook executed\" "+
+		runIDPattern+" hook=before",
+		system.GetTestingStderr())
+	assert.Regexp(t, "hook=after .* stdout=gallon-spoon-ramble",
+		system.GetTestingStdout())
+	assert.NotContains(t, system.GetTestingStdout(), "fact gathered")
+}
+
+// TestRunGatherFacts tests gathering facts without executing actions.
+//
+// It verifies that the facts are gathered and that a failing before hook
+// returns an OSError.
+func TestRunGatherFacts(t *testing.T) {
+	_ = system.LogInit(system.LogConfig{
+		File:  "testing_buffer",
+		Level: "debug",
+	})
+
+	// given: We define a configuration
+	config := Config{
+		Facts: []Fact{
+			{Name: "first", Command: "echo sharpen-dimmer-tidy"},
+		},
+		Actions: []Action{
+			{Command: "echo cradle-mutual-drench"},
+		},
+	}
+
+	// when: We gather the facts
+	facts, err := GatherFacts(context.Background(), config)
+
+	// then: We check that the facts were gathered without actions
+	assert.Nil(t, err)
+	assert.Equal(t, "sharpen-dimmer-tidy", facts["first"].Result.Stdout)
+	assert.NotContains(t, system.GetTestingStdout(), "action executed")
+
+	// when: We gather the facts with a failing before hook
+	config.Before = "exit 1"
+	facts, err = GatherFacts(context.Background(), config)
+
+	// then: We check that the facts were not gathered
+	assert.Nil(t, facts)
+	assertErrorName(t, "OSError", err)
+}
+
+// TestRunFactsUnchanged tests the Run function in daemon mode with facts
+// identical to the previous run.
+//
+// It verifies that the actions are skipped unless the run is forced.
+func TestRunFactsUnchanged(t *testing.T) {
+	DaemonMode = true
+	defer func() {
+		DaemonMode = false
+		ForceRun = false
+		factsHash = 0
+	}()
+
+	// given: We define a configuration file with a constant fact
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	actionsLog := filepath.Join(dir, "actions.log")
+	content := []byte(strings.ReplaceAll(`
+        daemon:
+          interval: 5s
+        facts:
+          - name: constant
+            command: echo constant
+        actions:
+          - command: echo action >> LOG
+    `, "LOG", actionsLog))
+	assert.Nil(t, os.WriteFile(file, content, 0600))
+	args := Config{
+		Logging: system.LogConfig{File: "testing_buffer", Level: "info"},
+	}
+
+	// when: We run the application twice
+	_, err := Run(context.Background(), file, args)
+	assert.Nil(t, err)
+	_, err = Run(context.Background(), file, args)
+	assert.Nil(t, err)
+
+	// then: We check that the actions were skipped in the second run
+	output, _ := os.ReadFile(actionsLog)
+	assert.Equal(t, "action\n", string(output))
+	assert.Contains(t, system.GetTestingStdout(),
+		"facts unchanged, skipping actions")
+
+	// when: We force the third run
+	ForceRun = true
+	_, err = Run(context.Background(), file, args)
+
+	// then: We check that the actions were executed
+	assert.Nil(t, err)
+	output, _ = os.ReadFile(actionsLog)
+	assert.Equal(t, "action\naction\n", string(output))
+}
+
+// TestRunFactsUnchangedRetry tests the daemon runs which do not skip
+// the actions although the facts are unchanged.
+//
+// It verifies that actions are executed again after a run with a failed
+// action and in every run of a configuration without facts.
+func TestRunFactsUnchangedRetry(t *testing.T) {
+	DaemonMode, ReloadDebounce = true, 0
+	defer func() {
+		DaemonMode, ReloadDebounce = false, 500*time.Millisecond
+		factsHash = 0
+	}()
+
+	for _, test := range []struct {
+		Facts  string
+		Action string
+	}{
+		{Facts: "[{name: constant, command: echo constant}]",
+			Action: "echo action >> LOG; exit 1"},
+		{Facts: "[]", Action: "echo action >> LOG"},
+	} {
+		// given: We define a configuration file
+		dir := t.TempDir()
+		file := filepath.Join(dir, "config.yaml")
+		actionsLog := filepath.Join(dir, "actions.log")
+		content := []byte(`daemon:
+  interval: 5s
+facts: ` + test.Facts + `
+actions:
+  - command: ` + strings.ReplaceAll(test.Action, "LOG", actionsLog) + `
+`)
+		assert.Nil(t, os.WriteFile(file, content, 0600))
+		args := Config{Logging: system.LogConfig{File: "testing_buffer"}}
+
+		// when: We run the application twice
+		for range 2 {
+			_, err := Run(context.Background(), file, args)
+			assert.Nil(t, err)
+		}
+
+		// then: We check that the actions were executed in both runs
+		output, _ := os.ReadFile(actionsLog)
+		assert.Equal(t, "action\naction\n", string(output), test.Action)
+	}
+}
+
+// TestRunLastRunFailed tests the LastRunFailed function.
+//
+// It verifies that a run is failed only if all of the executed actions
+// failed, and that runs executing no actions are successful.
+func TestRunLastRunFailed(t *testing.T) {
+	defer func() {
+		lastRunFailed = false
+	}()
+	for _, test := range []struct {
+		Actions  string
+		Expected bool
+	}{
+		{Actions: "[{command: 'false'}]", Expected: true},
+		{Actions: "[{command: 'false'}, {command: 'true'}]", Expected: false},
+		{Actions: "[{commands: ['false', 'true'], continue_on_failure: true}]",
+			Expected: true},
+		{Actions: "[{command: 'false', rules: ['false']}]", Expected: false},
+	} {
+		// given: We define a configuration file with the actions
+		file := filepath.Join(t.TempDir(), "config.yaml")
+		content := []byte("actions: " + test.Actions)
+		assert.Nil(t, os.WriteFile(file, content, 0600))
+		args := Config{Logging: system.LogConfig{File: "testing_buffer"}}
+
+		// when: We run the application
+		_, err := Run(context.Background(), file, args)
+
+		// then: We check the run status
+		assert.Nil(t, err)
+		assert.Equal(t, test.Expected, LastRunFailed(), test.Actions)
+	}
+}
+
+// TestRunFailedSkipped tests the runFailed function for skipped runs.
+//
+// It verifies that a run skipping the actions keeps the failure state of
+// the previous run unless it returned an error.
+func TestRunFailedSkipped(t *testing.T) {
+	defer func() {
+		lastRunFailed = false
+	}()
+	skipped := Results{Skipped: true}
+	for _, previous := range []bool{false, true} {
+		// given: The state of the previous run
+		lastRunFailed = previous
+
+		// when: We check the skipped runs
+		failed := runFailed(skipped, nil)
+		failedWithError := runFailed(skipped, errors.New("failed"))
+
+		// then: We check that only the error changes the state
+		assert.Equal(t, previous, failed)
+		assert.True(t, failedWithError)
+	}
+}
+
+// TestExecute tests the Execute function.
+//
+// It verifies that the facts and the results of the executed and skipped
+// actions are returned, and that an invalid configuration returns
+// a ValidationError instead of exiting.
+func TestExecute(t *testing.T) {
+	// given: We define a configuration
+	config := Config{
+		Defaults: Defaults{Environment: map[string]string{"SUFFIX": "!"}},
+		Facts: []Fact{
+			{Name: "greeting", Command: "echo hello"},
+		},
+		Actions: []Action{
+			{Command: "echo ${greeting}${SUFFIX}"},
+			{Command: "echo skipped", Rules: []string{"false"}},
+		},
+	}
+
+	// when: We execute the configuration
+	results, err := Execute(context.Background(), config)
+
+	// then: We check the results
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", results.Facts["greeting"].Result.Stdout)
+	assert.Len(t, results.Actions, 2)
+	assert.True(t, results.Actions[0].Executed)
+	assert.Equal(t, "hello!", results.Actions[0].Result.Stdout)
+	assert.False(t, results.Actions[1].Executed)
+
+	// when: We execute an invalid configuration
+	_, err = Execute(context.Background(), Config{})
+
+	// then: We check the validation error
+	assertErrorName(t, "ValidationError", err)
+}
+
+// TestExecuteConcurrent tests concurrent calls of Execute and Run.
+//
+// It verifies that the runs are serialized, run with -race to detect data
+// races on the state shared by the runs.
+func TestExecuteConcurrent(t *testing.T) {
+	DaemonMode, ReloadDebounce = true, 0
+	defer func() {
+		DaemonMode, ReloadDebounce = false, 500*time.Millisecond
+		factsHash = 0
+	}()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte(`daemon:
+  interval: 5s
+facts:
+  - name: constant
+    command: echo constant
+actions:
+  - command: "true"
+`), 0600))
+	args := Config{Logging: system.LogConfig{File: "testing_buffer"}}
+
+	// when: We call Execute and Run concurrently
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := Execute(context.Background(), Config{
+				Facts:   []Fact{{Name: "constant", Command: "echo constant"}},
+				Actions: []Action{{Command: "true"}},
+			})
+			assert.Nil(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := Run(context.Background(), file, args)
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// then: We check that the hash of the unchanged facts was saved
+	assert.NotZero(t, factsHash)
+}
+
+// TestExecuteCacheWithinRun tests reusing the results of identical
+// commands within a run.
+//
+// It verifies that facts with the same command are executed once per run
+// and again in the next run.
+func TestExecuteCacheWithinRun(t *testing.T) {
+	CacheWithinRun = true
+	defer func() {
+		CacheWithinRun = false
+	}()
+	counter := filepath.Join(t.TempDir(), "counter")
+	command := "echo run >> " + counter + "; wc -l < " + counter
+	config := Config{
+		Facts: []Fact{
+			{Name: "first", Command: command},
+			{Name: "second", Command: command},
+		},
+		Actions: []Action{{Command: "true"}},
+	}
+
+	for _, expected := range []string{"1", "2"} {
+		// when: We execute the configuration
+		results, err := Execute(context.Background(), config)
+
+		// then: We check that the command was executed once
+		assert.Nil(t, err)
+		assert.Equal(t, expected,
+			strings.TrimSpace(results.Facts["first"].Result.Stdout))
+		assert.Equal(t, results.Facts["first"].Result.Stdout,
+			results.Facts["second"].Result.Stdout)
+	}
+}
+
+// TestExecuteLogSummary tests the summary logged at the end of a run.
+//
+// It verifies that the summary is logged at info level also if no action
+// rules passed.
+func TestExecuteLogSummary(t *testing.T) {
+	for _, test := range []struct {
+		Actions  []Action
+		Expected string
+	}{
+		{Actions: []Action{
+			{Command: "exit 1"},
+			{Command: "true"},
+			{Command: "echo skipped", Rules: []string{"false"}},
+		}, Expected: "evaluated=3 executed=2 failed=1"},
+		{Actions: []Action{
+			{Command: "echo skipped", Rules: []string{"false"}},
+		}, Expected: "evaluated=1 executed=0 failed=0"},
+	} {
+		_ = system.LogInit(system.LogConfig{
+			File:  "testing_buffer",
+			Level: "info",
+		})
+
+		// when: We execute the configuration
+		_, err := Execute(context.Background(), Config{Actions: test.Actions})
+
+		// then: We check the logged summary
+		assert.Nil(t, err)
+		assert.Regexp(t, `level=INFO msg="run completed" `+test.Expected,
+			system.GetTestingStdout())
+	}
+}
+
+// TestExecuteShellArgs tests passing arguments to the shells of facts
+// and actions.
+//
+// It verifies that the arguments are passed to the shells of the fact
+// and action commands, e.g. "-e" exiting on the first error.
+func TestExecuteShellArgs(t *testing.T) {
+	config := Config{
+		Facts: []Fact{{Name: "errexit", Shell: "/bin/bash",
+			ShellArgs: []string{"-e"}, Command: "false; echo continued"}},
+		Actions: []Action{{Shell: "/bin/bash", ShellArgs: []string{"-e"},
+			Command: "echo $-; exit 2"}},
+	}
+
+	// when: We execute the configuration
+	results, err := Execute(context.Background(), config)
+
+	// then: We check that the shells exited on the first error
+	assert.Nil(t, err)
+	assert.Equal(t, 1, results.Facts["errexit"].Result.Rc)
+	assert.Equal(t, "", results.Facts["errexit"].Result.Stdout)
+	assert.Contains(t, results.Actions[0].Result.Stdout, "e")
+	assert.Equal(t, 2, results.Actions[0].Result.Rc)
 }
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// configShells returns the sorted distinct shells used by the facts
+// and actions of the configuration, including the default shell used by
+// the hooks and the commands without a shell set.
+func configShells(config Config) []string {
+	shells := []string{system.DefaultShell}
+	for _, fact := range config.Facts {
+		shells = append(shells, fact.Shell)
+	}
+	for _, action := range config.Actions {
+		shells = append(shells, action.Shell)
+	}
+	shells = slices.DeleteFunc(shells, func(shell string) bool {
+		return shell == ""
+	})
+	slices.Sort(shells)
+	return slices.Compact(shells)
+}
+
+// checkShells verifies that every shell used by the configuration exists
+// and is executable. It returns a ValidationError listing all missing
+// shells.
+func checkShells(config Config) error {
+	missing := []string{}
+	for _, shell := range configShells(config) {
+		if _, err := mockLookPath(shell); err != nil {
+			missing = append(missing, shell)
+		}
+	}
+	if len(missing) > 0 {
+		return system.WrapError(&ValidationError{Err: fmt.Errorf(
+			"shells not found: %s", strings.Join(missing, ", "))})
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var defaultLookPath = mockLookPath
+
+// mockInstalledShells mocks looking up the shells so that every shell
+// is found, e.g. to run a configuration using shells not installed
+// on the testing system.
+func mockInstalledShells(t *testing.T) {
+	mockLookPath = func(file string) (string, error) {
+		return file, nil
+	}
+	t.Cleanup(func() {
+		mockLookPath = defaultLookPath
+	})
+}
+
+// TestConfigShells tests collecting the distinct shells used
+// by the configuration.
+func TestConfigShells(t *testing.T) {
+	config := Config{
+		Facts: []Fact{
+			{Name: "first", Command: "true", Shell: "/bin/bash"},
+			{Name: "second", Command: "true"},
+		},
+		Actions: []Action{
+			{Command: "true", Shell: "/bin/zsh"},
+			{Command: "true", Shell: "/bin/bash"},
+		},
+	}
+	assert.Equal(t, []string{"/bin/bash", "/bin/sh", "/bin/zsh"},
+		configShells(config))
+}
+
+// TestCheckShells tests the checkShells function.
+//
+// It verifies that a single ValidationError lists all missing shells.
+func TestCheckShells(t *testing.T) {
+	// given: We define a configuration using missing shells
+	config := Config{
+		Facts: []Fact{
+			{Name: "fact", Command: "true", Shell: "/missing/zsh"},
+		},
+		Actions: []Action{
+			{Command: "true", Shell: "/missing/fish"},
+			{Command: "true", Shell: "/missing/zsh"},
+		},
+	}
+
+	// when: We check the shells
+	err := checkShells(config)
+
+	// then: We check the error
+	assertErrorName(t, "ValidationError", err)
+	assert.ErrorContains(t, err,
+		"shells not found: /missing/fish, /missing/zsh")
+
+	// when: We check the shells of a configuration without missing shells
+	config.Facts[0].Shell = ""
+	config.Actions = config.Actions[:0]
+
+	// then: We check that no error is returned
+	assert.Nil(t, checkShells(config))
+}
+
+// TestExecuteMissingShell tests that Execute fails before running any
+// command if a shell is missing.
+func TestExecuteMissingShell(t *testing.T) {
+	mockLookPath = func(file string) (string, error) {
+		return "", errors.New("not found")
+	}
+	defer func() {
+		mockLookPath = defaultLookPath
+	}()
+
+	// when: We execute a configuration
+	results, err := Execute(context.Background(), Config{
+		Actions: []Action{{Command: "true"}},
+	})
+
+	// then: We check that nothing was executed
+	assertErrorName(t, "ValidationError", err)
+	assert.Nil(t, results.Actions)
+}
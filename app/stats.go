@@ -0,0 +1,51 @@
+package app
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// mockNow allows mocking the current time in tests.
+var mockNow = time.Now
+
+// statPath checks the path without spawning a process. The result output
+// is "true" if the path exists, otherwise "false". The returned values
+// describe the path: whether it exists and, if it does, its type ("file",
+// "dir" or "other"), octal permission, size in bytes, modification time
+// as a Unix timestamp and age in seconds. Errors other than a missing path
+// are saved as the result error.
+func statPath(path string) (system.Command, map[string]string) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return system.Command{Stdout: "false"},
+			map[string]string{"exists": "false"}
+	}
+	if err != nil {
+		return system.Command{Rc: -1, Error: err}, nil
+	}
+	return system.Command{Stdout: "true"}, map[string]string{
+		"exists": "true",
+		"type":   fileType(info),
+		"mode":   "0" + strconv.FormatUint(uint64(info.Mode().Perm()), 8),
+		"size":   strconv.FormatInt(info.Size(), 10),
+		"mtime":  strconv.FormatInt(info.ModTime().Unix(), 10),
+		"age": strconv.FormatInt(
+			int64(mockNow().Sub(info.ModTime()).Seconds()), 10),
+	}
+}
+
+// fileType returns the type of the file: "file", "dir" or "other".
+func fileType(info fs.FileInfo) string {
+	switch {
+	case info.Mode().IsRegular():
+		return "file"
+	case info.IsDir():
+		return "dir"
+	}
+	return "other"
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Summary is the report of a run: the gathered facts, the results
+// of the actions and their durations, e.g. to be printed as JSON or YAML.
+type Summary struct {
+	Facts     []FactSummary   `json:"facts"`
+	Actions   []ActionSummary `json:"actions"`
+	Evaluated int             `json:"evaluated"`
+	Executed  int             `json:"executed"`
+	Failed    int             `json:"failed"`
+	Duration  string          `json:"duration"`
+}
+
+// FactSummary is the result of a fact in the summary of a run.
+type FactSummary struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Rc       int    `json:"rc"`
+	Duration string `json:"duration"`
+}
+
+// ActionSummary is the result of an action in the summary of a run.
+type ActionSummary struct {
+	Command  string `json:"command"`
+	Executed bool   `json:"executed"`
+	Failed   bool   `json:"failed"`
+	Rc       int    `json:"rc"`
+	Duration string `json:"duration"`
+}
+
+// Summary returns the summary of the run with the facts sorted by name
+// and the actions in configuration order. The action duration is the total
+// duration of its executed commands.
+func (r Results) Summary() Summary {
+	summary := Summary{
+		Facts:     []FactSummary{},
+		Actions:   []ActionSummary{},
+		Evaluated: len(r.Actions),
+		Duration:  r.Duration.String(),
+	}
+	for _, fact := range r.Facts {
+		value, _ := fact.value()
+		summary.Facts = append(summary.Facts, FactSummary{
+			Name:     fact.Name,
+			Value:    value,
+			Rc:       fact.Result.Rc,
+			Duration: fact.Result.Duration.String(),
+		})
+	}
+	slices.SortFunc(summary.Facts, func(a, b FactSummary) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, action := range r.Actions {
+		summary.Actions = append(summary.Actions, action.summary())
+		summary.Executed += btoi(action.Executed)
+		summary.Failed += btoi(action.Failed())
+	}
+	return summary
+}
+
+// summary returns the result of the action in the summary of a run.
+func (r ActionResult) summary() ActionSummary {
+	var duration time.Duration
+	for _, c := range r.Commands {
+		duration += c.Duration
+	}
+	return ActionSummary{
+		Command:  strings.Join(r.Action.CommandList(), "; "),
+		Executed: r.Executed,
+		Failed:   r.Failed(),
+		Rc:       r.Result.Rc,
+		Duration: duration.String(),
+	}
+}
+
+// btoi returns 1 for true and 0 for false.
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
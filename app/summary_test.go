@@ -0,0 +1,46 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestResultsSummary tests the summary of a run.
+//
+// It verifies that the facts are sorted by name, that the actions keep
+// the configuration order and that the totals and durations are set.
+func TestResultsSummary(t *testing.T) {
+	config := Config{
+		Facts: []Fact{
+			{Name: "zone", Command: "echo eu"},
+			{Name: "load", Command: "echo 3; exit 2"},
+		},
+		Actions: []Action{
+			{Command: "exit 1"},
+			{Command: "echo skipped", Rules: []string{"false"}},
+			{Commands: []string{"true", "echo done"}},
+		},
+	}
+
+	// when: We execute the configuration and summarize the results
+	results, err := Execute(context.Background(), config)
+	summary := results.Summary()
+
+	// then: We check the summary
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"load", "zone"}, []string{
+		summary.Facts[0].Name, summary.Facts[1].Name})
+	assert.Equal(t, 2, summary.Facts[0].Rc)
+	assert.Equal(t, "eu", summary.Facts[1].Value)
+	assert.NotEqual(t, "0s", summary.Facts[1].Duration)
+	assert.Equal(t, ActionSummary{Command: "echo skipped", Duration: "0s"},
+		summary.Actions[1])
+	assert.Equal(t, "true; echo done", summary.Actions[2].Command)
+	assert.True(t, summary.Actions[0].Failed)
+	assert.Equal(t, 1, summary.Actions[0].Rc)
+	assert.Equal(t, []int{3, 2, 1}, []int{summary.Evaluated,
+		summary.Executed, summary.Failed})
+	assert.NotEqual(t, "0s", summary.Duration)
+}
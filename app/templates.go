@@ -0,0 +1,32 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+)
+
+// renderCommand renders a command as a Go template with the fact values
+// as data, e.g. "deploy {{ .version }}". It returns an error if the
+// template is invalid or references an undefined fact.
+func renderCommand(command string, data map[string]string) (string, error) {
+	t, err := template.New("command").Option("missingkey=error").
+		Parse(command)
+	if err != nil {
+		return "", fmt.Errorf("parsing template %q: %w", command, err)
+	}
+
+	var rendered strings.Builder
+	if err := t.Execute(&rendered, data); err != nil {
+		return "", fmt.Errorf("rendering template %q: %w", command, err)
+	}
+	return rendered.String(), nil
+}
+
+// logRenderFailed logs a command that could not be rendered.
+func logRenderFailed(command string, err error) {
+	system.Log("error", "template rendering failed", "command", command,
+		"error", err)
+}
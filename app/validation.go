@@ -0,0 +1,149 @@
+package app
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+)
+
+// translator translates validation errors into English messages.
+var translator, _ = ut.New(en.New()).GetTranslator("en")
+
+// customTranslations maps the custom validation rules to their messages.
+var customTranslations = map[string]string{
+	"dirmode": "{0} must be an octal permission with full owner " +
+		"access, e.g. 0750",
+	"duration":      "{0} must be a valid duration, e.g. 30s",
+	"excluded_with": "{0} must not be set together with {1}",
+	"filemode": "{0} must be an octal permission with owner read " +
+		"and write, e.g. 0640",
+	"filepath":   "{0} must be a valid file path",
+	"identifier": "{0} must be a valid environment variable name",
+	"loglevel":   "{0} must be one of [trace debug info warn error]",
+	"regexp": "{0} must be a valid regular expression with a capture " +
+		"group",
+}
+
+// FieldError describes a configuration field failing a validation rule.
+type FieldError struct {
+	Field   string // field path, e.g. "actions[0].command"
+	Rule    string // validation rule, e.g. "required"
+	Message string // human-friendly message
+}
+
+// Error returns the message prefixed with the path of the parent field,
+// e.g. "actions[0]: command is a required field".
+func (e FieldError) Error() string {
+	i := strings.LastIndex(e.Field, ".")
+	if i < 0 {
+		return e.Message
+	}
+	return e.Field[:i] + ": " + e.Message
+}
+
+// ValidationErrors aggregates the errors of a configuration validation.
+type ValidationErrors []error
+
+// Error returns the messages of all validation errors.
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the validation errors.
+func (e ValidationErrors) Unwrap() []error {
+	return e
+}
+
+// newValidationErrors returns the validation errors which are not nil,
+// with the validator errors translated into field errors. It returns nil
+// if there are no errors.
+func newValidationErrors(errs ...error) error {
+	result := ValidationErrors{}
+	for _, err := range errs {
+		var fieldErrors validator.ValidationErrors
+		if errors.As(err, &fieldErrors) {
+			result = append(result, translateFieldErrors(fieldErrors)...)
+		} else if err != nil {
+			result = append(result, err)
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// translateFieldErrors translates the validator errors into field errors
+// with human-friendly messages naming the configuration fields.
+func translateFieldErrors(fieldErrors validator.ValidationErrors) []error {
+	result := []error{}
+	for _, fieldError := range fieldErrors {
+		// remove the name of the validated struct from the path
+		_, field, _ := strings.Cut(fieldError.Namespace(), ".")
+		result = append(result, FieldError{
+			Field:   field,
+			Rule:    fieldError.Tag(),
+			Message: fieldError.Translate(translator),
+		})
+	}
+	return result
+}
+
+// registerTranslations registers the English translations of the default
+// and custom validation rules, and names the fields after their keys
+// in the configuration file.
+func registerTranslations(validate *validator.Validate) error {
+	validate.RegisterTagNameFunc(yamlFieldName)
+	err := entranslations.RegisterDefaultTranslations(validate, translator)
+	if err != nil {
+		return err
+	}
+	for tag, message := range customTranslations {
+		err := validate.RegisterTranslation(tag, translator,
+			registerTranslation(tag, message), translateField)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerTranslation returns a function adding the message of the rule
+// to the translator.
+func registerTranslation(tag string,
+	message string) validator.RegisterTranslationsFunc {
+	return func(trans ut.Translator) error {
+		return trans.Add(tag, message, true)
+	}
+}
+
+// translateField translates the validation error of a field using
+// the message of its rule, filled with the field name and the lowercase
+// rule parameter, e.g. the names of related fields joined with "or".
+func translateField(trans ut.Translator, fe validator.FieldError) string {
+	param := strings.ReplaceAll(strings.ToLower(fe.Param()), " ", " or ")
+	message, err := trans.T(fe.Tag(), fe.Field(), param)
+	if err != nil {
+		return fe.Error()
+	}
+	return message
+}
+
+// yamlFieldName returns the key of the field in the configuration file:
+// the name set by the yaml tag, or the lowercase field name.
+func yamlFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestValidateTranslatedErrors tests translating validation errors into
+// human-friendly messages.
+//
+// It verifies that the messages name the fields by their keys
+// in the configuration file and that the errors keep the field path
+// and the validation rule.
+func TestValidateTranslatedErrors(t *testing.T) {
+	for _, test := range []struct {
+		Config   Config
+		Field    string
+		Rule     string
+		Expected string
+	}{
+		{Config: Config{}, Field: "actions", Rule: "required",
+			Expected: "actions is a required field"},
+		{Config: Config{Actions: []Action{{}}},
+			Field: "actions[0].command", Rule: "required_without",
+			Expected: "actions[0]: command is a required field"},
+		{Config: Config{Actions: []Action{{Command: "true",
+			Commands: []string{"true"}}}},
+			Field: "actions[0].command", Rule: "excluded_with",
+			Expected: "actions[0]: command must not be set together " +
+				"with commands"},
+		{Config: Config{Facts: []Fact{{Name: "fact", Command: "true",
+			File: "/etc/hostname"}}, Actions: []Action{{Command: "true"}}},
+			Field: "facts[0].file", Rule: "excluded_with",
+			Expected: "facts[0]: file must not be set together with " +
+				"command or http or stat"},
+		{Config: Config{Facts: []Fact{{Name: "fact"}},
+			Actions: []Action{{Command: "true"}}},
+			Field: "facts[0].command", Rule: "required_without_all",
+			Expected: "facts[0]: command is a required field"},
+		{Config: Config{Facts: []Fact{{Name: "fact", Command: "true",
+			HTTP: &HTTPProbe{URL: "http://localhost/"}}},
+			Actions: []Action{{Command: "true"}}},
+			Field: "facts[0].http", Rule: "excluded_with",
+			Expected: "facts[0]: http must not be set together with " +
+				"command or file or stat"},
+		{Config: Config{Facts: []Fact{{Name: "fact",
+			HTTP: &HTTPProbe{URL: "localhost"}}},
+			Actions: []Action{{Command: "true"}}},
+			Field: "facts[0].http.url", Rule: "url",
+			Expected: "facts[0].http: url must be a valid URL"},
+		{Config: Config{Actions: []Action{{Commands: []string{"true", ""}}}},
+			Field: "actions[0].commands[1]", Rule: "required",
+			Expected: "actions[0]: commands[1] is a required field"},
+		{Config: Config{Daemon: Daemon{RunTimeout: "soon"},
+			Actions: []Action{{Command: "true"}}},
+			Field: "daemon.run_timeout", Rule: "duration",
+			Expected: "daemon: run_timeout must be a valid duration, " +
+				"e.g. 30s"},
+		{Config: Config{Actions: []Action{{Command: "true",
+			LogLevel: "verbose"}}},
+			Field: "actions[0].log_level", Rule: "loglevel",
+			Expected: "actions[0]: log_level must be one of " +
+				"[trace debug info warn error]"},
+		{Config: Config{Facts: []Fact{{Name: "fact", Command: "true",
+			EnvName: "1st"}}, Actions: []Action{{Command: "true"}}},
+			Field: "facts[0].env_name", Rule: "identifier",
+			Expected: "facts[0]: env_name must be a valid environment " +
+				"variable name"},
+		{Config: Config{Facts: []Fact{{Name: "fact", Command: "true",
+			Regex: "load ([0-9]+"}}, Actions: []Action{{Command: "true"}}},
+			Field: "facts[0].regex", Rule: "regexp",
+			Expected: "facts[0]: regex must be a valid regular expression " +
+				"with a capture group"},
+		{Config: Config{Facts: []Fact{{Name: "fact", Command: "true",
+			Regex: "[0-9]+"}}, Actions: []Action{{Command: "true"}}},
+			Field: "facts[0].regex", Rule: "regexp",
+			Expected: "facts[0]: regex must be a valid regular expression " +
+				"with a capture group"},
+	} {
+		// when: We validate the configuration
+		err := test.Config.Validate()
+
+		// then: We check the field error
+		var fieldError FieldError
+		assert.True(t, errors.As(err, &fieldError), test.Expected)
+		assert.Equal(t, test.Field, fieldError.Field)
+		assert.Equal(t, test.Rule, fieldError.Rule)
+		assert.EqualError(t, err, test.Expected)
+	}
+}
+
+// TestValidationErrors tests aggregating validation errors.
+func TestValidationErrors(t *testing.T) {
+	first := errors.New("first")
+	second := FieldError{Field: "actions", Message: "second"}
+
+	// when: We aggregate the errors
+	err := newValidationErrors(nil, first, second, nil)
+
+	// then: We check the message and the unwrapped errors
+	assert.EqualError(t, err, "first; second")
+	assert.ErrorIs(t, err, first)
+	assert.Nil(t, newValidationErrors(nil, nil))
+}
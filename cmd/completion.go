@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// completionCmd represents the completion command
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate the autocompletion script for the specified shell",
+	Long: `Generate the autocompletion script for the specified shell.
+
+To load completions in the current bash session, run:
+
+  source <(yaml-runner-go completion bash)`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output := cmd.OutOrStdout()
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletionV2(output, true)
+		case "zsh":
+			return rootCmd.GenZshCompletion(output)
+		case "fish":
+			return rootCmd.GenFishCompletion(output, true)
+		case "powershell":
+			return rootCmd.GenPowerShellCompletionWithDesc(output)
+		default:
+			return fmt.Errorf("unsupported shell: %s", args[0])
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
@@ -1,6 +1,10 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
 	"time"
 
 	"github.com/piotr-ku/yaml-runner-go/app"
@@ -8,52 +12,201 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// HealthAddr is the address of the health HTTP endpoint.
+var HealthAddr string
+
+// MetricsEnabled enables serving Prometheus metrics on the health address.
+var MetricsEnabled bool
+
+// ForceRun disables skipping actions when the facts are unchanged.
+var ForceRun bool
+
+// RunOnce stops the daemon after the first run.
+var RunOnce bool
+
+// MaxRuns stops the daemon after the number of runs, unlimited if zero.
+var MaxRuns int
+
+// ReloadDebounce is the time the configuration files must stay unmodified
+// before they are reloaded.
+var ReloadDebounce time.Duration
+
+// healthShutdownTimeout is the time to wait for the health HTTP server
+// to finish serving requests on shutdown.
+const healthShutdownTimeout = 5 * time.Second
+
+// healthReadHeaderTimeout is the time allowed to read request headers
+// by the health HTTP server.
+const healthReadHeaderTimeout = 5 * time.Second
+
 // daemonCmd represents the daemon command
 var daemonCmd = &cobra.Command{
 	Use:   "daemon",
 	Short: "Run actions periodically in the background",
-	Run: func(_ *cobra.Command, _ []string) {
-		// Minimal logging level
-		level := "info"
-		if DebugMode {
-			level = "debug"
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		ctx := cmd.Context()
+		app.DaemonMode = true
+		app.ForceRun = ForceRun
+		app.ReloadDebounce = ReloadDebounce
+		if MaxRuns < 0 {
+			return system.WrapError(&app.ValidationError{
+				Err: errors.New("--max-runs must not be negative")})
 		}
-		overwrite := app.Config{
-			// Default daemon settings
-			Daemon: app.Daemon{
-				Interval: DaemonInterval,
-			},
-			// Default logging settings
-			Logging: system.LogConfig{
-				File:  LogFile,
-				Quiet: QuietMode,
-				JSON:  LogJSON,
-				Level: level,
-			},
+
+		overwrite := buildOverrideConfig(cmd)
+
+		// Start health HTTP server
+		health := app.NewHealth()
+		if HealthAddr != "" {
+			server, err := startHealthServer(HealthAddr, healthHandler(health))
+			if err != nil {
+				return err
+			}
+			defer stopHealthServer(server)
+		} else if MetricsEnabled {
+			return system.WrapError(&app.ValidationError{
+				Err: errors.New("--metrics requires --health-addr")})
 		}
 
-		for {
+		// Run until the context is cancelled or the number of runs is
+		// reached, runs are started one after another and never overlap
+		failures := 0
+		for runs := 1; ctx.Err() == nil; runs++ {
 			// Save start time
 			startTime := time.Now()
 			// Run application and save configuration
-			config := app.Run(ConfigFile, overwrite)
-			minInterval, _ := time.ParseDuration(config.Daemon.Interval)
+			config, err := app.Run(ctx, ConfigFile, overwrite)
+			runDuration := time.Since(startTime)
+			// Save run status
+			health.Record(startTime, runDuration, err)
+			if err != nil {
+				return err
+			}
+			// Warn if the run is too slow for the interval
+			config.Daemon.LogOverrun(runDuration)
+			// Count consecutive failed runs
+			failures = countFailures(failures)
 			// Calculate how long we should wait for the next run
-			stopTime := time.Now()
-			runDuration := stopTime.Sub(startTime)
-			// Sleep if run duration is less than minimal interval
-			if runDuration < time.Duration(minInterval) {
-				diff := minInterval.Milliseconds() - runDuration.Milliseconds()
-				wait := time.Duration(diff) * time.Millisecond
+			wait := nextWait(config.Daemon, startTime, failures)
+			// Stop after the last run if requested
+			if lastRun(runs) {
+				break
+			}
+			// Sleep until the next interval or scheduled time
+			if wait > 0 {
 				// Log
-				system.Log("debug", "sleeping", "ms", wait.Milliseconds())
-				// Wait
-				time.Sleep(wait)
+				system.Log("debug", "sleeping", "ms", wait.Milliseconds(),
+					"next_run", time.Now().Add(wait).Format(time.RFC3339))
+				// Wait unless the context is cancelled
+				select {
+				case <-ctx.Done():
+				case <-time.After(wait):
+				}
 			}
 		}
+
+		// Log daemon shutdown
+		system.Log("info", "stopping")
+		return lastRunError()
 	},
 }
 
+// errLastRunFailed is returned by the daemon if its last run failed.
+var errLastRunFailed = errors.New("last run failed")
+
+// lastRunError returns errLastRunFailed if the last run failed, so
+// the daemon exits with a non-zero status code, e.g. for supervisors.
+func lastRunError() error {
+	if app.LastRunFailed() {
+		return errLastRunFailed
+	}
+	return nil
+}
+
+// lastRun reports whether the daemon should stop after the number of
+// completed runs, set by --once and --max-runs.
+func lastRun(runs int) bool {
+	return RunOnce || (MaxRuns > 0 && runs >= MaxRuns)
+}
+
+// countFailures returns the number of consecutive failed runs including
+// the last run, which resets it if successful.
+func countFailures(failures int) int {
+	if app.LastRunFailed() {
+		return failures + 1
+	}
+	return 0
+}
+
+// nextWait returns how long the daemon should wait for the next run
+// started at start, backing off after the consecutive failed runs.
+func nextWait(daemon app.Daemon, start time.Time, failures int) time.Duration {
+	wait := daemon.Wait(start, time.Now())
+	backoff := daemon.Backoff(wait, failures)
+	if backoff > wait {
+		system.Log("warn", "runs failing, backing off", "failures", failures,
+			"ms", backoff.Milliseconds())
+	}
+	return backoff
+}
+
+// healthHandler returns an HTTP handler serving the health endpoints
+// and the metrics if enabled.
+func healthHandler(health *app.Health) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", health.Handler())
+	if MetricsEnabled {
+		mux.Handle("/metrics", app.EnableMetrics().Handler())
+	}
+	return mux
+}
+
+// startHealthServer starts the health HTTP server listening on the address.
+// It returns an IOError if the address cannot be listened on.
+func startHealthServer(addr string, handler http.Handler) (*http.Server,
+	error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, system.WrapError(&app.IOError{Err: err})
+	}
+
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: healthReadHeaderTimeout,
+	}
+	go func() {
+		err := server.Serve(listener)
+		if !errors.Is(err, http.ErrServerClosed) {
+			system.Log("error", "health server failed", "error", err)
+		}
+	}()
+
+	return server, nil
+}
+
+// stopHealthServer gracefully shuts down the health HTTP server.
+func stopHealthServer(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		healthShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		system.Log("error", "health server shutdown failed", "error", err)
+	}
+}
+
 func init() {
+	daemonCmd.Flags().StringVar(&HealthAddr, "health-addr", "",
+		"serve health endpoints on the address, e.g. :8080")
+	daemonCmd.Flags().BoolVar(&MetricsEnabled, "metrics", false,
+		"serve Prometheus metrics on the health address")
+	daemonCmd.Flags().BoolVar(&ForceRun, "force", false,
+		"run actions even if the facts are unchanged since the previous run")
+	daemonCmd.Flags().BoolVar(&RunOnce, "once", false,
+		"stop after the first run, e.g. to test the daemon configuration")
+	daemonCmd.Flags().IntVar(&MaxRuns, "max-runs", 0,
+		"stop after the number of runs, e.g. in tests, 0 for unlimited")
+	daemonCmd.Flags().DurationVar(&ReloadDebounce, "reload-debounce",
+		app.ReloadDebounce, "time the configuration files must stay "+
+			"unmodified before they are reloaded, 0 to disable")
 	rootCmd.AddCommand(daemonCmd)
 }
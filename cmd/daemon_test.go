@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/piotr-ku/yaml-runner-go/app"
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDaemonMaxRuns tests stopping the daemon after a number of runs.
+//
+// It verifies that the daemon loop exits once the number of runs set by
+// --max-runs is completed, with an error only if the last run executing
+// actions failed, and without executing actions with unchanged facts.
+func TestDaemonMaxRuns(t *testing.T) {
+	configFile, logFile, debounce := ConfigFile, LogFile, ReloadDebounce
+	defer func() {
+		ConfigFile, LogFile, ReloadDebounce = configFile, logFile, debounce
+		MaxRuns = 0
+		app.DaemonMode = false
+	}()
+	LogFile, ReloadDebounce, MaxRuns = "testing_buffer", 0, 3
+	daemonCmd.SetContext(context.Background())
+
+	for _, test := range []struct {
+		Command  string
+		Runs     string
+		Expected error
+	}{
+		{Command: "echo run >> runs.log", Runs: "run\n", Expected: nil},
+		{Command: "echo run >> runs.log; test -e failed || " +
+			"{ touch failed; exit 1; }", Runs: "run\nrun\n", Expected: nil},
+		{Command: "echo run >> runs.log; exit 1", Runs: "run\nrun\nrun\n",
+			Expected: errLastRunFailed},
+	} {
+		// given: An action with a constant fact
+		dir := t.TempDir()
+		ConfigFile = filepath.Join(dir, "config.yaml")
+		assert.Nil(t, os.WriteFile(ConfigFile, []byte(`daemon:
+  interval: 100ms
+facts:
+  - name: constant
+    command: echo constant
+actions:
+  - command: `+test.Command+`
+workdir: `+dir+`
+`), 0o600))
+
+		// when: We run the daemon
+		err := daemonCmd.RunE(daemonCmd, nil)
+
+		// then: We check that the daemon stopped after the runs
+		assert.Equal(t, test.Expected, err, test.Command)
+		runs, err := os.ReadFile(filepath.Join(dir, "runs.log"))
+		assert.Nil(t, err)
+		assert.Equal(t, test.Runs, string(runs), test.Command)
+	}
+}
+
+// TestDaemonBackoffUnchangedFacts tests backing off from failing runs
+// with unchanged facts.
+//
+// It verifies that runs with unchanged facts following failed runs
+// execute the actions and keep counting the consecutive failures.
+func TestDaemonBackoffUnchangedFacts(t *testing.T) {
+	configFile, logFile, debounce := ConfigFile, LogFile, ReloadDebounce
+	defer func() {
+		ConfigFile, LogFile, ReloadDebounce = configFile, logFile, debounce
+		MaxRuns = 0
+		app.DaemonMode = false
+	}()
+	LogFile, ReloadDebounce, MaxRuns = "testing_buffer", 0, 3
+	daemonCmd.SetContext(context.Background())
+
+	// given: A failing action with a constant fact
+	dir := t.TempDir()
+	ConfigFile = filepath.Join(dir, "config.yaml")
+	assert.Nil(t, os.WriteFile(ConfigFile, []byte(`daemon:
+  interval: 100ms
+  max_backoff: 1s
+  failure_threshold: 1
+facts:
+  - name: constant
+    command: echo constant
+actions:
+  - command: echo run >> runs.log; exit 1
+workdir: `+dir+`
+`), 0o600))
+
+	// when: We run the daemon
+	err := daemonCmd.RunE(daemonCmd, nil)
+
+	// then: We check that every run failed and the daemon backed off
+	assert.Equal(t, errLastRunFailed, err)
+	runs, err := os.ReadFile(filepath.Join(dir, "runs.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "run\nrun\nrun\n", string(runs))
+	assert.Contains(t, system.GetTestingStdout(), "failures=3")
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/spf13/cobra"
+)
+
+// ExitCodesOutput is the output format of the exit-codes command.
+var ExitCodesOutput string
+
+// exitCodeDescriptions describes the built-in exit status codes.
+var exitCodeDescriptions = map[string]string{
+	"OK":              "success",
+	"Unknown":         "unexpected error, e.g. an invalid flag",
+	"IOError":         "a file, directory or URL cannot be read",
+	"ParseError":      "a file cannot be parsed, e.g. invalid YAML",
+	"ValidationError": "the configuration or the flags are invalid",
+	"OSError":         "the operating system failed the run",
+}
+
+// exitCode represents an exit status code printed by the exit-codes
+// command.
+type exitCode struct {
+	Code        int    `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// exitCodesCmd represents the exit-codes command
+var exitCodesCmd = &cobra.Command{
+	Use:   "exit-codes",
+	Short: "Print the exit status codes and their meaning",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		codes := listExitCodes()
+		switch ExitCodesOutput {
+		case "json":
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(codes)
+		case "table":
+			return printExitCodesTable(cmd.OutOrStdout(), codes)
+		default:
+			return fmt.Errorf("unsupported output format: %s",
+				ExitCodesOutput)
+		}
+	},
+}
+
+// listExitCodes returns the exit status codes mapped to the error names,
+// including the registered ones, sorted by code and name.
+func listExitCodes() []exitCode {
+	codes := []exitCode{}
+	for name, code := range system.ErrorCodes() {
+		codes = append(codes, exitCode{Code: int(code), Name: name,
+			Description: exitCodeDescriptions[name]})
+	}
+	slices.SortFunc(codes, func(a, b exitCode) int {
+		if a.Code != b.Code {
+			return a.Code - b.Code
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return codes
+}
+
+// printExitCodesTable prints the exit status codes as a table.
+func printExitCodesTable(output io.Writer, codes []exitCode) error {
+	rows := []string{"CODE\tNAME\tDESCRIPTION"}
+	for _, code := range codes {
+		rows = append(rows, fmt.Sprintf("%d\t%s\t%s", code.Code, code.Name,
+			code.Description))
+	}
+
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	if _, err := io.WriteString(w, strings.Join(rows, "\n")+"\n"); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func init() {
+	exitCodesCmd.Flags().StringVar(&ExitCodesOutput, "output", "table",
+		"output format (table or json)")
+	rootCmd.AddCommand(exitCodesCmd)
+}
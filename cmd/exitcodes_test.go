@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPrintExitCodesTable tests printing the exit status codes.
+//
+// It verifies that the built-in codes are printed sorted by code with
+// their descriptions.
+func TestPrintExitCodesTable(t *testing.T) {
+	// when: We print the exit codes
+	var output bytes.Buffer
+	err := printExitCodesTable(&output, listExitCodes())
+
+	// then: We check the printed table
+	assert.Nil(t, err)
+	assert.Equal(t, `CODE  NAME             DESCRIPTION
+0     OK               success
+1     Unknown          unexpected error, e.g. an invalid flag
+64    IOError          a file, directory or URL cannot be read
+65    ParseError       a file cannot be parsed, e.g. invalid YAML
+66    ValidationError  the configuration or the flags are invalid
+67    OSError          the operating system failed the run
+`, output.String())
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/piotr-ku/yaml-runner-go/app"
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/spf13/cobra"
+)
+
+// FactsOutput is the output format of the facts command.
+var FactsOutput string
+
+// gatheredFact represents a fact printed by the facts command.
+type gatheredFact struct {
+	Name   string `json:"name"`
+	Rc     int    `json:"rc"`
+	Stdout string `json:"stdout"`
+}
+
+// factsCmd represents the facts command
+var factsCmd = &cobra.Command{
+	Use:   "facts",
+	Short: "Gather and print facts without executing actions",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		err := system.LogInit(buildOverrideConfig(cmd).Logging)
+		if err != nil {
+			return err
+		}
+
+		config, err := app.LoadConfig(ConfigFile)
+		if err != nil {
+			return err
+		}
+		facts, err := app.GatherFacts(cmd.Context(), config)
+		if err != nil {
+			return err
+		}
+		result := newGatheredFacts(config, facts)
+
+		switch FactsOutput {
+		case "json":
+			return printFactsJSON(cmd.OutOrStdout(), result)
+		case "table":
+			return printFactsTable(cmd.OutOrStdout(), result)
+		default:
+			return fmt.Errorf("unsupported output format: %s", FactsOutput)
+		}
+	},
+}
+
+// newGatheredFacts creates the list of gathered facts in the order
+// of the configuration, followed by the overridden facts not defined
+// in the configuration. Facts not gathered, e.g. due to a timeout,
+// are skipped.
+func newGatheredFacts(config app.Config, facts app.Facts) []gatheredFact {
+	names := []string{}
+	for _, fact := range config.Facts {
+		names = append(names, fact.Name)
+	}
+	extra := []string{}
+	for name := range facts {
+		if !slices.Contains(names, name) {
+			extra = append(extra, name)
+		}
+	}
+	slices.Sort(extra)
+
+	result := []gatheredFact{}
+	for _, name := range append(names, extra...) {
+		if fact, ok := facts[name]; ok {
+			result = append(result, gatheredFact{
+				Name:   fact.Name,
+				Rc:     fact.Result.Rc,
+				Stdout: fact.Result.Stdout,
+			})
+		}
+	}
+	return result
+}
+
+// printFactsJSON prints the gathered facts in JSON format.
+func printFactsJSON(output io.Writer, result []gatheredFact) error {
+	encoder := json.NewEncoder(output)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// printFactsTable prints the gathered facts as a table.
+func printFactsTable(output io.Writer, result []gatheredFact) error {
+	rows := []string{"FACT\tRC\tSTDOUT"}
+	for _, fact := range result {
+		rows = append(rows, fmt.Sprintf("%s\t%d\t%s", fact.Name, fact.Rc,
+			fact.Stdout))
+	}
+
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	if _, err := io.WriteString(w, strings.Join(rows, "\n")+"\n"); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func init() {
+	factsCmd.Flags().StringVar(&FactsOutput, "output", "table",
+		"output format (table or json)")
+	rootCmd.AddCommand(factsCmd)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/piotr-ku/yaml-runner-go/app"
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/spf13/cobra"
+)
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Evaluate rules and report the actions which would be executed",
+	Long: `Gathers facts and evaluates the rules of all actions without
+executing any action command. It prints whether every action would be
+executed and fails if a rule command errors, e.g. because of a syntax error,
+as opposed to a rule which does not pass.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		err := system.LogInit(buildOverrideConfig(cmd).Logging)
+		if err != nil {
+			return err
+		}
+
+		config, err := app.LoadConfig(ConfigFile)
+		if err != nil {
+			return err
+		}
+		results, err := app.Lint(cmd.Context(), config)
+		if err != nil {
+			return err
+		}
+		if err := printLintTable(cmd.OutOrStdout(), results); err != nil {
+			return err
+		}
+		return lintErrors(results)
+	},
+}
+
+// printLintTable prints the linted actions as a table followed by
+// the errors of their rules.
+func printLintTable(output io.Writer, results []app.ActionLint) error {
+	rows := []string{"ACTION\tFIRES\tERRORS"}
+	errs := []string{}
+	for _, result := range results {
+		command := strings.Join(result.Action.CommandList(), "; ")
+		rows = append(rows, fmt.Sprintf("%s\t%t\t%d", command, result.Fires,
+			len(result.Errors())))
+		for _, err := range result.Errors() {
+			errs = append(errs, fmt.Sprintf("%s: %s", command, err))
+		}
+	}
+
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	_, err := io.WriteString(w, strings.Join(rows, "\n")+"\n")
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		_, err = io.WriteString(output, "\n"+strings.Join(errs, "\n")+"\n")
+	}
+	return err
+}
+
+// lintErrors returns a ValidationError if a rule of the linted actions
+// errors.
+func lintErrors(results []app.ActionLint) error {
+	count := 0
+	for _, result := range results {
+		count += len(result.Errors())
+	}
+	if count == 0 {
+		return nil
+	}
+	return system.WrapError(&app.ValidationError{
+		Err: fmt.Errorf("%d rules failed with errors", count)})
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/piotr-ku/yaml-runner-go/app"
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/spf13/cobra"
+)
+
+// ListOutput is the output format of the list command.
+var ListOutput string
+
+// listedFact represents a fact printed by the list command.
+type listedFact struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	Shell   string `json:"shell"`
+}
+
+// listedAction represents an action printed by the list command.
+type listedAction struct {
+	Command string `json:"command"`
+	Rules   int    `json:"rules"`
+}
+
+// listing represents the facts and actions printed by the list command.
+type listing struct {
+	Facts   []listedFact   `json:"facts"`
+	Actions []listedAction `json:"actions"`
+}
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Print configured facts and actions without running them",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		// Initialize logging to report configuration errors
+		err := system.LogInit(buildOverrideConfig(cmd).Logging)
+		if err != nil {
+			return err
+		}
+
+		config, err := app.LoadConfig(ConfigFile)
+		if err != nil {
+			return err
+		}
+		// List only the facts and actions selected by the filter flags
+		result := newListing(config.Filtered())
+
+		switch ListOutput {
+		case "json":
+			return printListingJSON(cmd.OutOrStdout(), result)
+		case "table":
+			return printListingTable(cmd.OutOrStdout(), result)
+		default:
+			return fmt.Errorf("unsupported output format: %s", ListOutput)
+		}
+	},
+}
+
+// newListing creates a listing from the provided configuration.
+func newListing(config app.Config) listing {
+	result := listing{
+		Facts:   []listedFact{},
+		Actions: []listedAction{},
+	}
+	for _, fact := range config.Facts {
+		result.Facts = append(result.Facts, listedFact{
+			Name:    fact.Name,
+			Command: factSource(fact),
+			Shell:   fact.Shell,
+		})
+	}
+	for _, action := range config.Actions {
+		result.Actions = append(result.Actions, listedAction{
+			Command: strings.Join(action.CommandList(), "; "),
+			Rules:   len(action.Rules) + len(action.Conditions),
+		})
+	}
+	return result
+}
+
+// factSource returns the command of the fact, or the file, the URL or
+// the path the fact is gathered from.
+func factSource(fact app.Fact) string {
+	switch {
+	case fact.File != "":
+		return "file " + fact.File
+	case fact.HTTP != nil:
+		return "http " + fact.HTTP.URL
+	case fact.Stat != "":
+		return "stat " + fact.Stat
+	}
+	return fact.Command
+}
+
+// printListingJSON prints the listing in JSON format.
+func printListingJSON(output io.Writer, result listing) error {
+	encoder := json.NewEncoder(output)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// printListingTable prints the listing as tables of facts and actions.
+func printListingTable(output io.Writer, result listing) error {
+	rows := []string{"FACT\tCOMMAND\tSHELL"}
+	for _, fact := range result.Facts {
+		rows = append(rows, strings.Join(
+			[]string{fact.Name, fact.Command, fact.Shell}, "\t"))
+	}
+	rows = append(rows, "", "ACTION\tRULES")
+	for _, action := range result.Actions {
+		rows = append(rows, fmt.Sprintf("%s\t%d", action.Command,
+			action.Rules))
+	}
+
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	if _, err := io.WriteString(w, strings.Join(rows, "\n")+"\n"); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func init() {
+	listCmd.Flags().StringVar(&ListOutput, "output", "table",
+		"output format (table or json)")
+	rootCmd.AddCommand(listCmd)
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/piotr-ku/yaml-runner-go/app"
+	"github.com/stretchr/testify/assert"
+)
+
+// listConfig is a configuration with tagged facts and actions.
+const listConfig = `facts:
+  - name: load
+    command: cat /proc/loadavg
+    shell: /bin/bash
+    tags: [monitoring]
+  - name: hostname
+    command: hostname
+    shell: /bin/sh
+actions:
+  - name: restart
+    command: echo restart
+    rules:
+      - test $load -gt 5
+    tags: [monitoring]
+  - commands:
+      - echo cleanup
+      - echo done
+`
+
+// runList runs the list command with the configuration and returns its
+// output and error.
+func runList(t *testing.T, output string) (string, error) {
+	configFile, logFile, listOutput := ConfigFile, LogFile, ListOutput
+	defer func() {
+		ConfigFile, LogFile, ListOutput = configFile, logFile, listOutput
+		app.ResultsHook = nil
+		listCmd.SetOut(nil)
+	}()
+
+	ConfigFile = filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, os.WriteFile(ConfigFile, []byte(listConfig), 0o600))
+	LogFile, ListOutput = "testing_buffer", output
+	buffer := &bytes.Buffer{}
+	listCmd.SetOut(buffer)
+
+	assert.Nil(t, rootCmd.PersistentPreRunE(listCmd, nil))
+	err := listCmd.RunE(listCmd, nil)
+	return buffer.String(), err
+}
+
+// TestListOutput tests printing the configured facts and actions.
+//
+// It verifies the table and JSON formats and that unknown formats are
+// rejected.
+func TestListOutput(t *testing.T) {
+	for _, test := range []struct {
+		Output   string
+		Expected string
+	}{
+		{Output: "table", Expected: `FACT      COMMAND            SHELL
+load      cat /proc/loadavg  /bin/bash
+hostname  hostname           /bin/sh
+
+ACTION                   RULES
+echo restart             1
+echo cleanup; echo done  0
+`},
+		{Output: "json", Expected: `{
+  "facts": [
+    {
+      "name": "load",
+      "command": "cat /proc/loadavg",
+      "shell": "/bin/bash"
+    },
+    {
+      "name": "hostname",
+      "command": "hostname",
+      "shell": "/bin/sh"
+    }
+  ],
+  "actions": [
+    {
+      "command": "echo restart",
+      "rules": 1
+    },
+    {
+      "command": "echo cleanup; echo done",
+      "rules": 0
+    }
+  ]
+}
+`},
+	} {
+		// when: We run the list command with the output format
+		output, err := runList(t, test.Output)
+
+		// then: We check the printed facts and actions
+		assert.Nil(t, err)
+		assert.Equal(t, test.Expected, output, test.Output)
+	}
+
+	// when: We run the list command with an unknown output format
+	_, err := runList(t, "xml")
+
+	// then: We check that the format is rejected
+	assert.EqualError(t, err, "unsupported output format: xml")
+}
+
+// TestListFilters tests listing the facts and actions selected by
+// the filter flags.
+//
+// It verifies that facts and actions without the tags of --tags and
+// actions skipped by --skip-action are not listed.
+func TestListFilters(t *testing.T) {
+	defer func() {
+		Tags, SkipActions = nil, nil
+		app.Tags, app.SkipActions = nil, nil
+	}()
+
+	for _, test := range []struct {
+		Tags        []string
+		SkipActions []string
+		Expected    string
+	}{
+		{Tags: []string{"monitoring"}, Expected: `FACT  COMMAND            SHELL
+load  cat /proc/loadavg  /bin/bash
+
+ACTION        RULES
+echo restart  1
+`},
+		{SkipActions: []string{"restart"},
+			Expected: `FACT      COMMAND            SHELL
+load      cat /proc/loadavg  /bin/bash
+hostname  hostname           /bin/sh
+
+ACTION                   RULES
+echo cleanup; echo done  0
+`},
+	} {
+		// given: The filter flags
+		Tags, SkipActions = test.Tags, test.SkipActions
+
+		// when: We run the list command
+		output, err := runList(t, "table")
+
+		// then: We check that only the selected facts and actions are listed
+		assert.Nil(t, err)
+		assert.Equal(t, test.Expected, output)
+	}
+}
@@ -1,19 +1,45 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
 
+	"github.com/piotr-ku/yaml-runner-go/app"
+	"github.com/piotr-ku/yaml-runner-go/system"
 	"github.com/spf13/cobra"
 )
 
 var (
 	ConfigFile     string
+	ConfigDir      string
 	LogFile        string
 	LogJSON        bool
+	LogFormat      string
+	LogColor       bool
 	QuietMode      bool
+	QuietErrors    bool
 	DebugMode      bool
+	TraceMode      bool
+	LogLevel       string
 	DaemonInterval string
+	StrictMode     bool
+	CacheDir       string
+	CacheWithinRun bool
+	SetFacts       []string
+	EnvFile        string
+	OutputFormat   string
+	OutputFile     string
+	SkipFacts      []string
+	SkipActions    []string
+	OnlyFacts      []string
+	OnlyActions    []string
+	Tags           []string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -25,29 +51,217 @@ in a YAML file.`,
 in a YAML file. It can be run once or as a daemon to execute
 commands at specific intervals.`,
 	Args: cobra.NoArgs,
+	// Errors are logged by the FatalError handler
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		app.StrictValidation = StrictMode
+		app.CacheDir = CacheDir
+		app.CacheWithinRun = CacheWithinRun
+		app.ConfigDir = ConfigDir
+		app.SkipFacts, app.SkipActions = SkipFacts, SkipActions
+		app.OnlyFacts, app.OnlyActions = OnlyFacts, OnlyActions
+		app.Tags = Tags
+		// Load only the fragments unless the file is set explicitly
+		if ConfigDir != "" && !cmd.Flags().Changed("config") {
+			ConfigFile = ""
+		}
+		// Do not print usage for errors returned by the application
+		cmd.SilenceUsage = true
+
+		if err := validateFlags(); err != nil {
+			return err
+		}
+		app.ResultsHook = summaryHook(cmd.OutOrStdout())
+		// Keep the summary printed to stdout apart from the log
+		system.ConsoleToStderr = OutputFormat != "" && OutputFile == ""
+
+		if EnvFile != "" {
+			if err := app.LoadEnvFile(EnvFile); err != nil {
+				return err
+			}
+		}
+
+		overrides, err := app.ParseFactOverrides(SetFacts)
+		if err != nil {
+			return err
+		}
+		app.FactOverrides = overrides
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command
 // and sets flags appropriately. This is called by main.main().
-// It only needs to happen once to the rootCmd.
+// It only needs to happen once to the rootCmd. The command context is
+// cancelled when the application receives an interrupt or termination signal.
+// Errors are logged and the application exits with the status code mapped
+// to the error.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err) // nolint:revive
-		os.Exit(1)       // nolint:revive
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt,
+		syscall.SIGTERM)
+	defer stop()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		stop()
+		system.FatalError(err)
+	}
+}
+
+// validateFlags returns a ValidationError if a flag is set to an unknown
+// value.
+func validateFlags() error {
+	if err := validateLogLevel(); err != nil {
+		return err
+	}
+	return validateOutputFormat()
+}
+
+// validLogLevels lists the log levels accepted by the --log-level flag,
+// besides their aliases.
+var validLogLevels = []string{"trace", "debug", "info", "warn", "error"}
+
+// validateLogLevel returns a ValidationError if the --log-level flag is
+// set to an unknown level.
+func validateLogLevel() error {
+	if LogLevel == "" ||
+		slices.Contains(validLogLevels, system.NormalizeLevel(LogLevel)) {
+		return nil
+	}
+	return system.WrapError(&app.ValidationError{Err: fmt.Errorf(
+		"invalid log level %q, expected one of %s", LogLevel,
+		strings.Join(validLogLevels, ", "))})
+}
+
+// logLevel returns the minimal log level set by the flags: the --log-level
+// flag, trace if the --trace flag is set, debug if the --debug flag is set,
+// otherwise info.
+func logLevel() string {
+	switch {
+	case LogLevel != "":
+		return system.NormalizeLevel(LogLevel)
+	case TraceMode:
+		return "trace"
+	case DebugMode:
+		return "debug"
+	}
+	return "info"
+}
+
+// defaultCacheDir returns the default directory for cached fact results in
+// the cache directory of the user, or in the temporary directory if the
+// user has none.
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "yaml-runner-go")
+}
+
+// buildOverrideConfig returns the configuration set by the flags, which
+// overrides the configuration file and the environment variables.
+// The log level is set only if a log level flag is set, so it does not
+// override the level of the configuration file and the environment.
+func buildOverrideConfig(cmd *cobra.Command) app.Config {
+	config := app.Config{
+		// Default daemon settings
+		Daemon: app.Daemon{
+			Interval: DaemonInterval,
+		},
+		// Default logging settings
+		Logging: system.LogConfig{
+			File: LogFile,
+		},
+	}
+	if slices.ContainsFunc(levelFlags, cmd.Flags().Changed) {
+		config.Logging.Level = logLevel()
+	}
+	setExplicitFlags(cmd, &config)
+	return config
+}
+
+// levelFlags lists the flags setting the log level.
+var levelFlags = []string{"log-level", "trace", "debug"}
+
+// setExplicitFlags sets the options of the configuration whose flags are
+// set on the command line explicitly, so they override the configuration
+// file also when set to false or empty values, e.g. --log "" to log
+// to the console only.
+func setExplicitFlags(cmd *cobra.Command, config *app.Config) {
+	if cmd.Flags().Changed("quiet") && LogLevel == "" {
+		config.SetQuiet(QuietMode)
+	}
+	if cmd.Flags().Changed("json") {
+		config.SetJSON(LogJSON)
+	}
+	if cmd.Flags().Changed("log-format") {
+		config.Logging.Format = LogFormat
+	}
+	if cmd.Flags().Changed("quiet-except-errors") {
+		config.Logging.QuietExceptErrors = QuietErrors
+	}
+	if cmd.Flags().Changed("color") {
+		config.Logging.Color = LogColor
+	}
+	if cmd.Flags().Changed("log") {
+		config.SetLogFile(LogFile)
+	}
+	if cmd.Flags().Changed("interval") {
+		config.SetInterval(DaemonInterval)
 	}
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&ConfigFile, "config", "./config.yaml",
 		"configuration file in yaml format")
+	rootCmd.PersistentFlags().StringVar(&ConfigDir, "config-dir", "",
+		"directory of yaml configuration files merged after --config")
 	rootCmd.PersistentFlags().StringVar(&DaemonInterval, "interval", "",
 		"set daemon interval")
 	rootCmd.PersistentFlags().StringVar(&LogFile, "log", "",
 		"enable logging to the file")
 	rootCmd.PersistentFlags().BoolVar(&LogJSON, "json", false,
 		"enable JSON formatting for the output")
+	rootCmd.PersistentFlags().StringVar(&LogFormat, "log-format", "",
+		"log format: text, json, logfmt or console (colored on terminals)")
+	rootCmd.PersistentFlags().BoolVar(&LogColor, "color", false,
+		"color the log levels on terminals")
 	rootCmd.PersistentFlags().BoolVar(&QuietMode, "quiet", false,
 		"enable quiet mode")
+	rootCmd.PersistentFlags().BoolVar(&QuietErrors, "quiet-except-errors",
+		false, "write errors to stderr in quiet mode")
 	rootCmd.PersistentFlags().BoolVar(&DebugMode, "debug", false,
 		"enable debug logging")
+	rootCmd.PersistentFlags().BoolVar(&TraceMode, "trace", false,
+		"log the environment and settings of every command, "+
+			"implies debug logging")
+	rootCmd.PersistentFlags().StringVar(&LogLevel, "log-level", "",
+		"minimal log level: trace, debug, info, warn or error, "+
+			"overrides --debug, --trace and --quiet")
+	rootCmd.PersistentFlags().BoolVar(&StrictMode, "strict", false,
+		"treat rules referencing undefined facts as validation errors")
+	rootCmd.PersistentFlags().StringVar(&CacheDir, "cache-dir",
+		defaultCacheDir(),
+		"directory for cached fact results")
+	rootCmd.PersistentFlags().BoolVar(&CacheWithinRun, "cache-within-run",
+		false, "execute identical commands once per run, reusing the result")
+	rootCmd.PersistentFlags().StringArrayVar(&SetFacts, "set-fact", nil,
+		"override a fact value without running its command (NAME=VALUE)")
+	rootCmd.PersistentFlags().StringArrayVar(&SkipFacts, "skip-fact", nil,
+		"do not gather the fact (repeatable)")
+	rootCmd.PersistentFlags().StringArrayVar(&SkipActions, "skip-action", nil,
+		"do not execute the action with the name or command (repeatable)")
+	rootCmd.PersistentFlags().StringArrayVar(&OnlyFacts, "only-fact", nil,
+		"gather only the fact (repeatable)")
+	rootCmd.PersistentFlags().StringArrayVar(&OnlyActions, "only-action", nil,
+		"execute only the action with the name or command (repeatable)")
+	rootCmd.PersistentFlags().StringSliceVar(&Tags, "tags", nil,
+		"gather and execute only the facts and actions with the tags, "+
+			"\"untagged\" selects those without tags")
+	rootCmd.PersistentFlags().StringVar(&EnvFile, "env-file", "",
+		"load environment variables from the file, e.g. .env")
+	rootCmd.PersistentFlags().StringVar(&OutputFormat, "output-format", "",
+		"print a summary of every run: text, json or yaml")
+	rootCmd.PersistentFlags().StringVar(&OutputFile, "output-file", "",
+		"write the run summary to the file instead of stdout")
 }
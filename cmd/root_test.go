@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/piotr-ku/yaml-runner-go/app"
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/stretchr/testify/assert"
+)
+
+// testedFlags lists the flags mapped to the configuration.
+var testedFlags = []string{"debug", "trace", "log-level", "quiet", "json",
+	"log", "interval", "log-format", "quiet-except-errors", "color"}
+
+// resetFlags restores the default values of the tested flags.
+func resetFlags(t *testing.T) {
+	for _, name := range testedFlags {
+		flag := rootCmd.Flags().Lookup(name)
+		assert.Nil(t, flag.Value.Set(flag.DefValue))
+		flag.Changed = false
+	}
+}
+
+// TestBuildOverrideConfig tests mapping the flags to the configuration
+// overriding the configuration file.
+//
+// It verifies the default settings, the log level resolution and that
+// the flags set explicitly override also false and empty values.
+func TestBuildOverrideConfig(t *testing.T) {
+	explicit := app.Config{
+		Logging: system.LogConfig{Format: "console",
+			QuietExceptErrors: true, Color: true},
+	}
+	explicit.SetQuiet(true)
+	explicit.SetJSON(false)
+	explicit.SetLogFile("")
+	explicit.SetInterval("5s")
+	logFile := app.Config{Logging: system.LogConfig{Level: "debug"}}
+	logFile.SetLogFile("runner.log")
+
+	for _, test := range []struct {
+		Args     []string
+		Expected app.Config
+	}{
+		{Args: []string{}, Expected: app.Config{}},
+		{Args: []string{"--debug", "--log", "runner.log"},
+			Expected: logFile},
+		{Args: []string{"--debug", "--trace"},
+			Expected: app.Config{Logging: system.LogConfig{Level: "trace"}}},
+		{Args: []string{"--debug", "--quiet", "--log-level", "WARNING"},
+			Expected: app.Config{Logging: system.LogConfig{Level: "warn"}}},
+		{Args: []string{"--quiet", "--json=false", "--log", "",
+			"--interval", "5s", "--log-format", "console",
+			"--quiet-except-errors", "--color"},
+			Expected: explicit},
+	} {
+		// given: We parse the flags
+		assert.Nil(t, rootCmd.ParseFlags(test.Args))
+
+		// when: We build the configuration
+		config := buildOverrideConfig(rootCmd)
+
+		// then: We check the mapped configuration
+		assert.Equal(t, test.Expected, config, test.Args)
+		resetFlags(t)
+	}
+}
+
+// TestDefaultCacheDir tests the default directory for cached fact results.
+//
+// It verifies that the cache directory of the user is used rather than
+// the temporary directory shared by all users.
+func TestDefaultCacheDir(t *testing.T) {
+	// given: We set the cache directory of the user
+	t.Setenv("XDG_CACHE_HOME", "/home/user/.cache")
+
+	// when: We get the default cache directory
+	dir := defaultCacheDir()
+
+	// then: We check that it is in the cache directory of the user
+	assert.Equal(t, "/home/user/.cache/yaml-runner-go", dir)
+}
+
+// TestOneshotLogLevelEnvironment tests the log level set by the environment
+// variable without log level flags.
+//
+// It verifies that the flags do not override the log level of
+// the environment unless they are set.
+func TestOneshotLogLevelEnvironment(t *testing.T) {
+	configFile, logFile := ConfigFile, LogFile
+	defer func() {
+		ConfigFile, LogFile = configFile, logFile
+	}()
+	t.Setenv("YRG_LOG_LEVEL", "debug")
+
+	// given: A configuration file and no flags
+	dir := t.TempDir()
+	ConfigFile = filepath.Join(dir, "config.yaml")
+	assert.Nil(t, os.WriteFile(ConfigFile, []byte(`actions:
+  - command: echo restart
+`), 0o600))
+	LogFile = "testing_buffer"
+	oneshotCmd.SetContext(context.Background())
+
+	// when: We run the oneshot command
+	assert.Nil(t, rootCmd.PersistentPreRunE(oneshotCmd, nil))
+	err := oneshotCmd.RunE(oneshotCmd, nil)
+
+	// then: We check that debug messages were logged
+	assert.Nil(t, err)
+	assert.Contains(t, system.GetTestingStdout(),
+		"level=DEBUG msg=\"action executed\"")
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/piotr-ku/yaml-runner-go/app"
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"gopkg.in/yaml.v3"
+)
+
+// validOutputFormats lists the formats accepted by the --output-format flag.
+var validOutputFormats = []string{"text", "json", "yaml"}
+
+// validateOutputFormat returns a ValidationError if the --output-format
+// flag is set to an unknown format.
+func validateOutputFormat() error {
+	if OutputFormat == "" || slices.Contains(validOutputFormats, OutputFormat) {
+		return nil
+	}
+	return system.WrapError(&app.ValidationError{Err: fmt.Errorf(
+		"invalid output format %q, expected one of %s", OutputFormat,
+		strings.Join(validOutputFormats, ", "))})
+}
+
+// summaryHook returns the results hook writing the summary of every run
+// in the format set by --output-format to the file set by --output-file,
+// or to the output if the file is not set. It returns nil if the format
+// is not set. Write errors are logged.
+func summaryHook(output io.Writer) func(app.Results) {
+	if OutputFormat == "" {
+		return nil
+	}
+	return func(results app.Results) {
+		err := writeSummary(output, results.Summary())
+		if err != nil {
+			system.Log("error", "cannot write run summary", "error", err)
+		}
+	}
+}
+
+// writeSummary writes the summary to the file set by --output-file,
+// truncating it, or to the output if the file is not set.
+func writeSummary(output io.Writer, summary app.Summary) error {
+	if OutputFile == "" {
+		return printSummary(output, OutputFormat, summary)
+	}
+	file, err := os.Create(OutputFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return printSummary(file, OutputFormat, summary)
+}
+
+// printSummary prints the summary in the format: text, json or yaml.
+func printSummary(output io.Writer, format string, summary app.Summary) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(output)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summary)
+	case "yaml":
+		encoder := yaml.NewEncoder(output)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(summary); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case "text":
+		return printSummaryText(output, summary)
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// printSummaryText prints the summary as tables of facts and actions
+// followed by the totals.
+func printSummaryText(output io.Writer, summary app.Summary) error {
+	rows := []string{"FACT\tRC\tDURATION\tVALUE"}
+	for _, fact := range summary.Facts {
+		rows = append(rows, fmt.Sprintf("%s\t%d\t%s\t%s", fact.Name, fact.Rc,
+			fact.Duration, fact.Value))
+	}
+	rows = append(rows, "", "ACTION\tEXECUTED\tFAILED\tRC\tDURATION")
+	for _, action := range summary.Actions {
+		rows = append(rows, fmt.Sprintf("%s\t%t\t%t\t%d\t%s", action.Command,
+			action.Executed, action.Failed, action.Rc, action.Duration))
+	}
+	rows = append(rows, "", fmt.Sprintf(
+		"evaluated: %d, executed: %d, failed: %d, duration: %s",
+		summary.Evaluated, summary.Executed, summary.Failed,
+		summary.Duration))
+
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	if _, err := io.WriteString(w, strings.Join(rows, "\n")+"\n"); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/piotr-ku/yaml-runner-go/app"
+	"github.com/piotr-ku/yaml-runner-go/system"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPrintSummary tests printing the run summary.
+//
+// It verifies the text, JSON and YAML formats and that unknown formats
+// are rejected.
+func TestPrintSummary(t *testing.T) {
+	summary := app.Summary{
+		Facts: []app.FactSummary{{Name: "load", Value: "3", Rc: 0,
+			Duration: "2ms"}},
+		Actions: []app.ActionSummary{{Command: "echo restart",
+			Executed: true, Failed: false, Rc: 0, Duration: "1ms"}},
+		Evaluated: 1, Executed: 1, Failed: 0, Duration: "4ms",
+	}
+
+	for _, test := range []struct {
+		Format   string
+		Expected string
+	}{
+		{Format: "text", Expected: `FACT  RC  DURATION  VALUE
+load  0   2ms       3
+
+ACTION        EXECUTED  FAILED  RC  DURATION
+echo restart  true      false   0   1ms
+
+evaluated: 1, executed: 1, failed: 0, duration: 4ms
+`},
+		{Format: "json", Expected: `{
+  "facts": [
+    {
+      "name": "load",
+      "value": "3",
+      "rc": 0,
+      "duration": "2ms"
+    }
+  ],
+  "actions": [
+    {
+      "command": "echo restart",
+      "executed": true,
+      "failed": false,
+      "rc": 0,
+      "duration": "1ms"
+    }
+  ],
+  "evaluated": 1,
+  "executed": 1,
+  "failed": 0,
+  "duration": "4ms"
+}
+`},
+		{Format: "yaml", Expected: `facts:
+  - name: load
+    value: "3"
+    rc: 0
+    duration: 2ms
+actions:
+  - command: echo restart
+    executed: true
+    failed: false
+    rc: 0
+    duration: 1ms
+evaluated: 1
+executed: 1
+failed: 0
+duration: 4ms
+`},
+	} {
+		// when: We print the summary
+		var output bytes.Buffer
+		err := printSummary(&output, test.Format, summary)
+
+		// then: We check the printed summary
+		assert.Nil(t, err, test.Format)
+		assert.Equal(t, test.Expected, output.String(), test.Format)
+	}
+
+	// when: We print the summary in an unknown format
+	err := printSummary(&bytes.Buffer{}, "xml", summary)
+
+	// then: We check the error
+	assert.EqualError(t, err, "unsupported output format: xml")
+}
+
+// TestOneshotSummaryStdout tests printing the run summary to stdout.
+//
+// It runs the oneshot command with --output-format and verifies that
+// stdout contains only the JSON summary and the log is written to stderr.
+func TestOneshotSummaryStdout(t *testing.T) {
+	configFile, logFile, stdout, stderr := ConfigFile, LogFile, os.Stdout,
+		os.Stderr
+	defer func() {
+		ConfigFile, LogFile, os.Stdout, os.Stderr = configFile, logFile,
+			stdout, stderr
+		OutputFormat, app.ResultsHook = "", nil
+		system.ConsoleToStderr = false
+	}()
+
+	// given: A configuration and files capturing stdout and stderr
+	dir := t.TempDir()
+	ConfigFile = filepath.Join(dir, "config.yaml")
+	assert.Nil(t, os.WriteFile(ConfigFile, []byte(`facts:
+  - name: load
+    command: echo 3
+actions:
+  - command: echo restart
+`), 0o600))
+	os.Stdout, _ = os.Create(filepath.Join(dir, "stdout"))
+	defer os.Stdout.Close()
+	os.Stderr, _ = os.Create(filepath.Join(dir, "stderr"))
+	defer os.Stderr.Close()
+	LogFile, OutputFormat = "", "json"
+	oneshotCmd.SetContext(context.Background())
+
+	// when: We run the oneshot command
+	assert.Nil(t, rootCmd.PersistentPreRunE(oneshotCmd, nil))
+	err := oneshotCmd.RunE(oneshotCmd, nil)
+
+	// then: We check that stdout is the summary and stderr the log
+	assert.Nil(t, err)
+	output, _ := os.ReadFile(os.Stdout.Name())
+	var summary app.Summary
+	assert.Nil(t, json.Unmarshal(output, &summary), string(output))
+	assert.Equal(t, 1, summary.Executed)
+	log, _ := os.ReadFile(os.Stderr.Name())
+	assert.Contains(t, string(log), "run completed")
+}
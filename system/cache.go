@@ -0,0 +1,75 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// commandCache memoizes the results of commands by their invocation.
+type commandCache struct {
+	mutex   sync.Mutex
+	results map[uint64]Command
+}
+
+// commandCacheKey is the context key of the command cache.
+type commandCacheKey struct{}
+
+// WithCommandCache returns a context memoizing the results of the commands
+// executed with it, e.g. within a single run, so identical invocations
+// (the same shell, command, directory, environment and output options)
+// are executed once. Commands writing to output files or streaming their
+// output are not memoized.
+func WithCommandCache(parent context.Context) context.Context {
+	cache := &commandCache{results: map[uint64]Command{}}
+	return context.WithValue(parent, commandCacheKey{}, cache)
+}
+
+// cachedCommand returns the command cache of the context and the cache
+// key of the command, or nil if the command is not memoized.
+func (c *Command) cachedCommand(ctx context.Context) (*commandCache,
+	uint64) {
+	cache, _ := ctx.Value(commandCacheKey{}).(*commandCache)
+	if cache == nil || c.StdoutFile != "" || c.StderrFile != "" ||
+		c.Stream {
+		return nil, 0
+	}
+	return cache, c.cacheKey()
+}
+
+// cacheKey returns the hash of the invocation of the command.
+func (c *Command) cacheKey() uint64 {
+	env := c.environment()
+	slices.Sort(env)
+	hash := fnv.New64a()
+	_, _ = fmt.Fprintf(hash, "%s\x00%q\x00%s\x00%s\x00%t\x00%t\x00%d\x00%s",
+		c.Shell, c.ShellArgs, c.Command, c.Directory, c.CombineOutput,
+		c.TrimOutput, c.MaxOutputBytes, strings.Join(env, "\x00"))
+	return hash.Sum64()
+}
+
+// load copies the cached result of the command and returns true if found.
+func (cache *commandCache) load(key uint64, c *Command) bool {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	result, ok := cache.results[key]
+	if ok {
+		c.Stdout, c.Stderr = result.Stdout, result.Stderr
+		c.Rc, c.Error, c.TimedOut = result.Rc, result.Error, result.TimedOut
+		c.Duration = result.Duration
+	}
+	return ok
+}
+
+// save saves the result of the command unless it timed out.
+func (cache *commandCache) save(key uint64, c *Command) {
+	if c.TimedOut {
+		return
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.results[key] = *c
+}
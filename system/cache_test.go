@@ -0,0 +1,59 @@
+package system
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCommandCache tests memoizing the results of commands executed
+// with a command cache context.
+//
+// It counts the executions of the command in a file and verifies that
+// identical invocations are executed once, while invocations with
+// a different environment or output file, and without the cache, are
+// executed again.
+func TestCommandCache(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "counter")
+	command := "echo run >> " + counter + "; wc -l < " + counter
+	ctx := WithCommandCache(context.Background())
+	execute := func(ctx context.Context, configure func(*Command)) string {
+		cmd := NewCommand(command)
+		configure(&cmd)
+		assert.Nil(t, cmd.Execute(ctx))
+		return strings.TrimSpace(cmd.Stdout)
+	}
+	unchanged := func(*Command) {}
+
+	// when: We execute identical commands
+	first := execute(ctx, unchanged)
+	second := execute(ctx, unchanged)
+
+	// then: We check that the result was reused
+	assert.Equal(t, "1", first)
+	assert.Equal(t, "1", second)
+
+	// when: We execute a command with another environment
+	environment := execute(ctx, func(cmd *Command) {
+		cmd.Environment = map[string]string{"NAME": "value"}
+	})
+
+	// then: We check that the command was executed
+	assert.Equal(t, "2", environment)
+
+	// when: We execute commands with an output file and without the cache
+	outputFile := execute(ctx, func(cmd *Command) {
+		cmd.StdoutFile = counter + ".out"
+	})
+	uncached := execute(context.Background(), unchanged)
+
+	// then: We check that the commands were executed
+	assert.Equal(t, "3", outputFile)
+	assert.Equal(t, "4", uncached)
+	content, _ := os.ReadFile(counter)
+	assert.Equal(t, 4, strings.Count(string(content), "run"))
+}
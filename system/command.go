@@ -3,28 +3,93 @@ package system
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
 // Command represents a system command to be executed.
 type Command struct {
-	Command     string            // The command to be executed.
-	Environment map[string]string // Environment variables for the command.
-	Directory   string            // Working directory for the command.
-	Timeout     int               // Timeout duration in seconds.
-	Shell       string            // Shell used to execute the command.
-	Stdout      string            // Standard output of the command.
-	Stderr      string            // Standard error of the command.
-	Rc          int               // Return code of the command.
-	Error       error             // Error encountered during command execution.
+	Command       string            // The command to be executed.
+	Environment   map[string]string // Environment variables for the command.
+	Directory     string            // Working directory for the command.
+	Timeout       int               // Timeout duration in seconds.
+	Shell         string            // Shell used to execute the command.
+	CombineOutput bool              // Whether to interleave stderr with stdout.
+	TrimOutput    bool              // Whether to trim newlines from the output.
+	Stdout        string            // Standard output of the command.
+	Stderr        string            // Standard error of the command.
+	Rc            int               // Return code of the command.
+	Error         error             // Error encountered during command execution.
+	TimedOut      bool              // Whether the command timed out.
+	Duration      time.Duration     // Time spent executing the command.
+
+	// Whether to start from an empty environment instead of the parent one,
+	// passing only the allowlisted parent variables, e.g. PATH.
+	CleanEnvironment bool
+	// Names of the parent environment variables passed to the command.
+	// All variables are passed when empty. Names can contain wildcards.
+	EnvAllow []string
+	// Names of the parent environment variables not passed to the command.
+	// It takes precedence over EnvAllow. Names can contain wildcards.
+	EnvDeny []string
+	// Files the standard output and standard error are written to
+	// in addition to being captured. The files are truncated first.
+	StdoutFile string
+	StderrFile string
+	// Maximum number of bytes of stdout and stderr each kept in memory,
+	// unlimited if zero. Longer output is truncated with TruncatedMarker.
+	// The output files receive the whole output.
+	MaxOutputBytes int
+	// Whether to log every line of the output at debug level while
+	// the command runs, in addition to capturing the whole output.
+	Stream bool
+	// Arguments passed to the shell before "-c", e.g. "-l" to start
+	// a login shell or "-e" to exit on the first error.
+	ShellArgs []string
 }
 
 var functionGetwd = os.Getwd
 
+// DefaultShell is the shell used to execute commands without a shell set.
+const DefaultShell = "/bin/sh"
+
+// cleanEnvironmentAllowlist lists the parent environment variables
+// passed to commands with a clean environment.
+var cleanEnvironmentAllowlist = []string{"PATH"}
+
+// waitDelay is the time to wait for the output pipes to be closed after
+// the command was killed, e.g. by child processes still holding them.
+const waitDelay = 100 * time.Millisecond
+
+// TimeoutRc is the return code of commands killed by their timeout,
+// as used by GNU timeout.
+const TimeoutRc = 124
+
+// TruncatedMarker is appended to output truncated to MaxOutputBytes.
+const TruncatedMarker = "[truncated]"
+
+// NotStartedRc is the return code of commands which could not be started,
+// e.g. because the shell does not exist, as used by shells for commands
+// not found.
+const NotStartedRc = 127
+
+// ErrNotStarted is wrapped by the error of a command which could not be
+// started, as opposed to a command finished with a non-zero return code.
+var ErrNotStarted = errors.New("command not started")
+
+// outputFilePermission is the permission of the created output files.
+const outputFilePermission fs.FileMode = 0600
+
 // NewCommand creates a new Command with default settings.
 func NewCommand(command string) Command {
 	pwd, err := functionGetwd()
@@ -33,43 +98,369 @@ func NewCommand(command string) Command {
 		panic(err.Error())
 	}
 	return Command{
-		Command:   command,
-		Directory: pwd,
-		Timeout:   timeout,
-		Shell:     "/bin/sh",
+		Command:    command,
+		Directory:  pwd,
+		Timeout:    timeout,
+		Shell:      DefaultShell,
+		TrimOutput: true,
 	}
 }
 
-// Execute executes the command and captures its output.
-func (c *Command) Execute() error {
+// Execute executes the command and captures its output. The command is
+// killed when the parent context is cancelled or the command timeout
+// is exceeded. A command killed by the timeout, or by the deadline
+// of the parent context, is marked as TimedOut with TimeoutRc. A command
+// which could not be started gets NotStartedRc and an error wrapping
+// ErrNotStarted. If the number of running commands is limited, it waits
+// for a running command to finish first. If the context memoizes commands
+// (see WithCommandCache), the result of an identical invocation is reused.
+func (c *Command) Execute(parent context.Context) error {
+	cache, key := c.cachedCommand(parent)
+	if cache == nil {
+		return c.execute(parent)
+	}
+	if cache.load(key, c) {
+		_ = Log("debug", "command result reused", "command", c.Command,
+			"rc", c.Rc)
+		return c.Error
+	}
+	err := c.execute(parent)
+	cache.save(key, c)
+	return err
+}
+
+// execute executes the command and captures its output.
+func (c *Command) execute(parent context.Context) error {
+	// Wait for a free process slot
+	release, err := acquireProcess(parent)
+	if err != nil {
+		c.Rc = -1
+		c.Error = err
+		return err
+	}
+	defer release()
+
+	// Measure the execution time
+	startTime := time.Now()
+	defer func() { c.Duration = time.Since(startTime) }()
+
 	// Set command timeout
-	ctx, cancel := context.WithTimeout(context.Background(),
+	ctx, cancel := context.WithTimeout(parent,
 		time.Duration(c.Timeout)*time.Second)
 	defer cancel()
 
 	// Set command with context
-	cmd := exec.CommandContext(ctx, c.Shell, "-c", c.Command)
+	cmd := exec.CommandContext(ctx, c.Shell, c.shellArgs()...)
+	cmd.WaitDelay = waitDelay
 
 	// Set environment variables
-	cmd.Env = os.Environ()
-	for key, value := range c.Environment {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%v", key, value))
-	}
+	cmd.Env = c.environment()
 
 	// Set working directory
 	cmd.Dir = c.Directory
 
-	// Capture stdout/stderr
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Run()
+	// Log the environment and settings in trace mode
+	c.logTrace(cmd.Env)
+
+	// Open output files
+	files, err := c.openOutputFiles()
+	defer closeOutputFiles(files)
+	if err != nil {
+		c.Rc = -1
+		c.Error = err
+		return err
+	}
+
+	// Capture stdout/stderr and write it to the output files
+	stdout := &limitedBuffer{limit: c.MaxOutputBytes}
+	stderr := &limitedBuffer{limit: c.MaxOutputBytes}
+	stdoutLines, stderrLines := c.lineLogger("stdout"), c.lineLogger("stderr")
+	cmd.Stdout = streamWriter(teeWriter(stdout, files[0]), stdoutLines)
+	cmd.Stderr = streamWriter(teeWriter(stderr, files[1]), stderrLines)
+	if c.CombineOutput {
+		// stdout and stderr are copied by separate goroutines unless
+		// they are the same writer, so the shared writer is locked
+		combined := &lockedWriter{w: cmd.Stdout}
+		cmd.Stdout = combined
+		cmd.Stderr = teeWriter(combined, files[1])
+	}
+	err = cmd.Run()
+	stdoutLines.Flush()
+	stderrLines.Flush()
 
 	// Save command stdout/stderr and return code
-	c.Stdout = strings.Trim(stdout.String(), "\n")
-	c.Stderr = strings.Trim(stderr.String(), "\n")
+	c.Stdout = c.output("stdout", stdout)
+	c.Stderr = c.output("stderr", stderr)
+	c.saveResult(ctx, cmd, err)
+
+	return c.Error
+}
+
+// output returns the output captured in the buffer, trimmed if requested.
+// Truncated output gets TruncatedMarker appended and a warning is logged.
+func (c *Command) output(stream string, buffer *limitedBuffer) string {
+	output := buffer.String()
+	if c.TrimOutput {
+		output = strings.Trim(output, "\n")
+	}
+	if buffer.truncated {
+		_ = Log("warn", "command output truncated", "command", c.Command,
+			"stream", stream, "max_output_bytes", c.MaxOutputBytes)
+		output += TruncatedMarker
+	}
+	return output
+}
+
+// saveResult saves the return code and the error of the executed command.
+// Commands which could not be started get NotStartedRc and an error
+// wrapping ErrNotStarted, commands killed by the timeout are marked as
+// TimedOut with TimeoutRc.
+func (c *Command) saveResult(ctx context.Context, cmd *exec.Cmd, err error) {
+	// The process state is not set if the command was not started
+	if cmd.ProcessState == nil && err != nil {
+		c.Rc = NotStartedRc
+		c.Error = fmt.Errorf("%w: %w", ErrNotStarted, err)
+		return
+	}
 	c.Rc = cmd.ProcessState.ExitCode()
 	c.Error = err
 
-	return err
+	// Mark the command killed by the timeout
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		c.Rc = TimeoutRc
+		c.TimedOut = true
+	}
+}
+
+// openOutputFiles opens the stdout and stderr files of the command.
+// The files not set are nil.
+func (c *Command) openOutputFiles() ([2]*os.File, error) {
+	files := [2]*os.File{}
+	for i, path := range []string{c.StdoutFile, c.StderrFile} {
+		if path == "" {
+			continue
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC,
+			outputFilePermission)
+		if err != nil {
+			return files, err
+		}
+		files[i] = f
+	}
+	return files, nil
+}
+
+// closeOutputFiles closes the opened output files.
+func closeOutputFiles(files [2]*os.File) {
+	for _, f := range files {
+		if f != nil {
+			_ = f.Close()
+		}
+	}
+}
+
+// maxLineBytes is the maximum length of a streamed output line unless
+// MaxOutputBytes is set.
+const maxLineBytes = 64 << 10
+
+// lineLogger logs the output of a command line by line at debug level.
+type lineLogger struct {
+	command string
+	stream  string
+	limit   int
+	partial []byte
+}
+
+// lineLogger returns a line logger of the output stream, or nil if
+// the output is not streamed. Lines are split at MaxOutputBytes, or at
+// maxLineBytes if it is not set.
+func (c *Command) lineLogger(stream string) *lineLogger {
+	if !c.Stream {
+		return nil
+	}
+	limit := c.MaxOutputBytes
+	if limit <= 0 {
+		limit = maxLineBytes
+	}
+	return &lineLogger{command: c.Command, stream: stream, limit: limit}
+}
+
+// Write logs the complete lines and keeps the last incomplete line until
+// the next write. Incomplete lines reaching the limit are logged, so
+// output without newlines does not grow the kept line without bound.
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.partial = append(l.partial, p...)
+	for {
+		line, rest, found := bytes.Cut(l.partial, []byte("\n"))
+		if !found {
+			break
+		}
+		l.log(line)
+		l.partial = rest
+	}
+	for len(l.partial) >= l.limit {
+		l.log(l.partial[:l.limit])
+		l.partial = l.partial[l.limit:]
+	}
+	return len(p), nil
+}
+
+// Flush logs the last line if it does not end with a newline.
+func (l *lineLogger) Flush() {
+	if l != nil && len(l.partial) > 0 {
+		l.log(l.partial)
+		l.partial = nil
+	}
+}
+
+// log logs a single line of the output.
+func (l *lineLogger) log(line []byte) {
+	_ = Log("debug", "command output", "command", l.command,
+		"stream", l.stream, "line", string(line))
+}
+
+// streamWriter returns a writer writing to the line logger in addition
+// to the writer, or the writer itself if the line logger is nil.
+func streamWriter(w io.Writer, lines *lineLogger) io.Writer {
+	if lines == nil {
+		return w
+	}
+	return io.MultiWriter(w, lines)
+}
+
+// limitedBuffer is a buffer keeping at most limit bytes, unlimited if
+// the limit is zero. Writes beyond the limit are discarded, so the command
+// is not interrupted.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write appends the bytes fitting the limit to the buffer. It always
+// reports the whole slice as written.
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.limit <= 0 || b.Len()+len(p) <= b.limit {
+		return b.Buffer.Write(p)
+	}
+	b.truncated = true
+	b.Buffer.Write(p[:max(b.limit-b.Len(), 0)])
+	return len(p), nil
+}
+
+// lockedWriter is a writer safe for concurrent writes.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write writes the bytes to the writer holding the lock.
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// teeWriter returns a writer duplicating its writes to the file,
+// or the writer itself if the file is nil.
+func teeWriter(w io.Writer, f *os.File) io.Writer {
+	if f == nil {
+		return w
+	}
+	return io.MultiWriter(w, f)
+}
+
+// environment returns the environment of the command: the parent
+// environment, or only its allowlisted variables in clean mode, filtered
+// by EnvAllow and EnvDeny, followed by the command environment variables.
+func (c *Command) environment() []string {
+	env := []string{}
+	for _, variable := range c.parentEnvironment() {
+		name, _, _ := strings.Cut(variable, "=")
+		if c.passVariable(name) {
+			env = append(env, variable)
+		}
+	}
+	for key, value := range c.Environment {
+		env = append(env, fmt.Sprintf("%s=%v", key, value))
+	}
+	return env
+}
+
+// parentEnvironment returns the parent environment variables, or only
+// the allowlisted ones in clean mode.
+func (c *Command) parentEnvironment() []string {
+	if !c.CleanEnvironment {
+		return os.Environ()
+	}
+	env := []string{}
+	for _, key := range cleanEnvironmentAllowlist {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
+// passVariable returns true if the parent environment variable should be
+// passed to the command according to EnvAllow and EnvDeny.
+func (c *Command) passVariable(name string) bool {
+	if matchesAny(c.EnvDeny, name) {
+		return false
+	}
+	return len(c.EnvAllow) == 0 || matchesAny(c.EnvAllow, name)
+}
+
+// matchesAny returns true if the name matches any of the patterns.
+// Invalid patterns do not match.
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// maskedValue replaces the values of secret environment variables
+// in trace log messages.
+const maskedValue = "***"
+
+// secretNamePattern matches the names of environment variables whose
+// values are masked in trace log messages, e.g. API_TOKEN.
+var secretNamePattern = regexp.MustCompile(
+	`(?i)secret|passw(or)?d|token|key|credential|auth|private`)
+
+// shellArgs returns the arguments of the shell: the ShellArgs followed by
+// "-c" and the command.
+func (c *Command) shellArgs() []string {
+	return append(slices.Clone(c.ShellArgs), "-c", c.Command)
+}
+
+// logTrace logs the environment passed to the command, with the values
+// of secret variables masked, and its shell, directory and timeout
+// at trace level. Nothing is computed unless trace messages are logged.
+func (c *Command) logTrace(env []string) {
+	if !traceEnabled() {
+		return
+	}
+	_ = Log("trace", "executing command", "command", c.Command,
+		"shell", c.Shell, "shell_args", c.ShellArgs, "dir", c.Directory,
+		"timeout", time.Duration(c.Timeout)*time.Second,
+		"environment", maskEnvironment(env))
+}
+
+// maskEnvironment returns the environment variables as a map with
+// the values of secret variables masked. Later values of a variable
+// override earlier ones, as when executing the command.
+func maskEnvironment(env []string) map[string]string {
+	masked := map[string]string{}
+	for _, variable := range env {
+		name, value, _ := strings.Cut(variable, "=")
+		if value != "" && secretNamePattern.MatchString(name) {
+			value = maskedValue
+		}
+		masked[name] = value
+	}
+	return masked
 }
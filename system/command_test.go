@@ -1,9 +1,13 @@
 package system
 
 import (
+	"context"
 	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -32,6 +36,7 @@ func TestNewCommand(t *testing.T) {
 		{Expected: pwd, Got: c.Directory, Desc: "directory"},
 		{Expected: timeout, Got: c.Timeout, Desc: "timeout"},
 		{Expected: "/bin/sh", Got: c.Shell, Desc: "shell"},
+		{Expected: true, Got: c.TrimOutput, Desc: "trim output"},
 		{Expected: "", Got: c.Stdout, Desc: "stdout"},
 		{Expected: "", Got: c.Stderr, Desc: "stderr"},
 		{Expected: 0, Got: c.Rc, Desc: "return code"},
@@ -87,7 +92,7 @@ func TestCommand(t *testing.T) {
 		// run command
 		cmd := NewCommand(command.Command)
 		cmd.Shell = "/bin/bash"
-		_ = cmd.Execute()
+		_ = cmd.Execute(context.Background())
 
 		var tests = []struct {
 			Expected    any
@@ -116,7 +121,7 @@ func TestCommandEnviroment(t *testing.T) {
 	// run command
 	cmd := NewCommand(command)
 	cmd.Environment = map[string]string{"VAR1": "test"}
-	_ = cmd.Execute()
+	_ = cmd.Execute(context.Background())
 
 	// Verify expected stdout
 	assert.Equal(t, "test", cmd.Stdout)
@@ -131,7 +136,7 @@ func TestCommandWorkingDirectory(t *testing.T) {
 	// run command
 	cmd := NewCommand(command)
 	cmd.Directory = "/"
-	_ = cmd.Execute()
+	_ = cmd.Execute(context.Background())
 
 	// Verify expected stdout
 	assert.Equal(t, "/", cmd.Stdout)
@@ -146,8 +151,399 @@ func TestCommandShell(t *testing.T) {
 	// run command
 	cmd := NewCommand(command)
 	cmd.Shell = "/bin/bash"
-	_ = cmd.Execute()
+	_ = cmd.Execute(context.Background())
 
 	// Verify expected stdout
 	assert.Equal(t, "/bin/bash", cmd.Stdout)
 }
+
+// TestCommandShellArgs tests the arguments passed to the shell.
+//
+// It verifies that the arguments are passed before "-c", e.g. "-l" starting
+// a login shell and "-e" exiting on the first error, and that the shell
+// is invoked as before without arguments.
+func TestCommandShellArgs(t *testing.T) {
+	for _, test := range []struct {
+		args   []string
+		stdout string
+		rc     int
+	}{
+		{args: nil, stdout: "non-login", rc: 0},
+		{args: []string{"-l"}, stdout: "login", rc: 0},
+		{args: []string{"-e"}, stdout: "", rc: 3},
+	} {
+		// given: We define a bash command
+		cmd := NewCommand("(exit 3); shopt -q login_shell && echo login " +
+			"|| echo non-login")
+		cmd.Shell = "/bin/bash"
+		cmd.ShellArgs = test.args
+
+		// when: We execute the command
+		_ = cmd.Execute(context.Background())
+
+		// then: We check the output and the return code
+		assert.Equal(t, test.stdout, cmd.Stdout, test.args)
+		assert.Equal(t, test.rc, cmd.Rc, test.args)
+	}
+}
+
+// TestCommandParentContext tests that the command respects the parent
+// context.
+//
+// It executes a long-running command with a parent context that is
+// cancelled after a short time and verifies that the command was killed
+// before its own timeout.
+func TestCommandParentContext(t *testing.T) {
+	const parentTimeout = 100 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), parentTimeout)
+	defer cancel()
+
+	// run command
+	cmd := NewCommand("sleep 3")
+	startTime := time.Now()
+	err := cmd.Execute(ctx)
+
+	// Verify the command was killed by the parent context
+	assert.NotNil(t, err)
+	assert.NotEqual(t, 0, cmd.Rc)
+	assert.Less(t, time.Since(startTime), time.Duration(cmd.Timeout)*
+		time.Second)
+}
+
+// TestCommandTimeout tests a command exceeding its timeout.
+//
+// It executes a command sleeping longer than its timeout and verifies that
+// the command was killed and marked as timed out with TimeoutRc.
+func TestCommandTimeout(t *testing.T) {
+	// run command
+	cmd := NewCommand("sleep 3")
+	cmd.Timeout = 1
+	err := cmd.Execute(context.Background())
+
+	// Verify the command was marked as timed out
+	assert.NotNil(t, err)
+	assert.True(t, cmd.TimedOut)
+	assert.Equal(t, TimeoutRc, cmd.Rc)
+
+	// Verify a failing command is not marked as timed out
+	cmd = NewCommand("exit 3")
+	_ = cmd.Execute(context.Background())
+	assert.False(t, cmd.TimedOut)
+	assert.Equal(t, 3, cmd.Rc)
+}
+
+// TestCommandNotStarted tests a command which cannot be started.
+//
+// It executes a command with a non-existent shell and verifies that
+// the startup failure is distinguished from a non-zero return code.
+func TestCommandNotStarted(t *testing.T) {
+	// run command
+	cmd := NewCommand("echo test")
+	cmd.Shell = "/not/existing/shell"
+	err := cmd.Execute(context.Background())
+
+	// Verify the command was not started
+	assert.ErrorIs(t, err, ErrNotStarted)
+	assert.ErrorIs(t, cmd.Error, ErrNotStarted)
+	assert.Equal(t, NotStartedRc, cmd.Rc)
+	assert.False(t, cmd.TimedOut)
+
+	// Verify a failing command was started
+	cmd = NewCommand("exit 127")
+	err = cmd.Execute(context.Background())
+	assert.NotErrorIs(t, err, ErrNotStarted)
+	assert.Equal(t, NotStartedRc, cmd.Rc)
+}
+
+// TestCommandMaxOutputBytes tests the maximum output size of the command.
+//
+// It executes commands writing more and less than the limit and verifies
+// that only the longer output is truncated while the file receives it all.
+func TestCommandMaxOutputBytes(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "stdout")
+	// run command
+	cmd := NewCommand("head -c 10000 /dev/zero | tr '\\0' a; echo err 1>&2")
+	cmd.MaxOutputBytes = 100
+	cmd.StdoutFile = file
+	err := cmd.Execute(context.Background())
+
+	// Verify the output was truncated
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Repeat("a", 100)+TruncatedMarker, cmd.Stdout)
+	assert.Equal(t, "err", cmd.Stderr)
+	content, _ := os.ReadFile(file)
+	assert.Len(t, content, 10000)
+
+	// Verify the output is unlimited by default
+	cmd = NewCommand("head -c 10000 /dev/zero")
+	_ = cmd.Execute(context.Background())
+	assert.Len(t, cmd.Stdout, 10000)
+}
+
+// TestCommandStream tests streaming the output of the command.
+//
+// It executes a command in streaming mode and verifies that every line,
+// including the last one without a newline, is logged while the whole
+// output is still captured.
+func TestCommandStream(t *testing.T) {
+	_ = LogInit(LogConfig{File: "testing_buffer", Level: "debug"})
+	// run command
+	cmd := NewCommand("echo one; echo two 1>&2; printf three")
+	cmd.Stream = true
+	err := cmd.Execute(context.Background())
+
+	// Verify the lines were logged
+	assert.Nil(t, err)
+	assert.Equal(t, "one\nthree", cmd.Stdout)
+	assert.Equal(t, "two", cmd.Stderr)
+	for _, line := range []string{"stream=stdout line=one",
+		"stream=stderr line=two", "stream=stdout line=three"} {
+		assert.Contains(t, GetTestingStdout(), line)
+	}
+
+	// Verify the lines are not logged by default
+	_ = LogInit(LogConfig{File: "testing_buffer", Level: "debug"})
+	cmd = NewCommand("echo one")
+	_ = cmd.Execute(context.Background())
+	assert.NotContains(t, GetTestingStdout(), "command output")
+}
+
+// TestCommandStreamLongLine tests streaming output without newlines.
+//
+// It verifies that incomplete lines are logged once they reach
+// MaxOutputBytes, or maxLineBytes if it is not set.
+func TestCommandStreamLongLine(t *testing.T) {
+	for _, test := range []struct {
+		MaxOutputBytes int
+		Lines          []string
+	}{
+		{MaxOutputBytes: 4, Lines: []string{"0123", "4567", "89"}},
+		{MaxOutputBytes: 0, Lines: []string{"0123456789"}},
+	} {
+		_ = LogInit(LogConfig{File: "testing_buffer", Level: "debug"})
+		// run command
+		cmd := NewCommand("printf 0123456789")
+		cmd.MaxOutputBytes = test.MaxOutputBytes
+		cmd.Stream = true
+		_ = cmd.Execute(context.Background())
+
+		// Verify the line was split at the limit
+		logged := GetTestingStdout()
+		assert.Equal(t, len(test.Lines),
+			strings.Count(logged, "msg=\"command output\""))
+		for _, line := range test.Lines {
+			assert.Contains(t, logged, "line="+line+"\n")
+		}
+	}
+
+	// Verify the kept line is limited
+	lines := &lineLogger{limit: 4}
+	_, _ = lines.Write([]byte("0123456789"))
+	assert.Equal(t, "89", string(lines.partial))
+}
+
+// TestCommandTrace tests logging the environment and settings
+// of the command at trace level.
+//
+// It verifies that the values of secret variables are masked and that
+// nothing is logged at debug level.
+func TestCommandTrace(t *testing.T) {
+	_ = LogInit(LogConfig{File: "testing_buffer", Level: "trace"})
+	// run command
+	cmd := NewCommand("true")
+	cmd.CleanEnvironment = true
+	cmd.Environment = map[string]string{
+		"REGION":    "eu-west-1",
+		"API_TOKEN": "legume-arrival-sprout",
+		"EMPTY_KEY": "",
+	}
+	_ = cmd.Execute(context.Background())
+
+	// Verify the trace message
+	logged := GetTestingStdout()
+	assert.Regexp(t, `level=TRACE msg="executing command" command=true `+
+		`shell=/bin/sh shell_args=\[\] dir=[^ ]+ timeout=5s `+
+		`environment="map\[`, logged)
+	assert.Contains(t, logged, "API_TOKEN:***")
+	assert.Contains(t, logged, "EMPTY_KEY: ")
+	assert.Contains(t, logged, "REGION:eu-west-1")
+	assert.NotContains(t, logged, "legume-arrival-sprout")
+
+	// Verify nothing is logged at debug level
+	_ = LogInit(LogConfig{File: "testing_buffer", Level: "debug"})
+	_ = cmd.Execute(context.Background())
+	assert.NotContains(t, GetTestingStdout(), "executing command")
+}
+
+// TestCommandCombineOutput tests the combined output of the command.
+//
+// It executes a command writing to both stdout and stderr and verifies
+// that the output is interleaved in stdout and stderr is empty.
+func TestCommandCombineOutput(t *testing.T) {
+	command := "echo line1; echo line2 1>&2; echo line3"
+	// run command
+	cmd := NewCommand(command)
+	cmd.CombineOutput = true
+	_ = cmd.Execute(context.Background())
+
+	// Verify expected stdout and stderr
+	assert.Equal(t, "line1\nline2\nline3", cmd.Stdout)
+	assert.Equal(t, "", cmd.Stderr)
+}
+
+// TestCommandRawOutput tests the command output without trimming.
+//
+// It executes a command with trailing newlines and verifies that the output
+// is trimmed only when TrimOutput is set.
+func TestCommandRawOutput(t *testing.T) {
+	command := "printf 'test\\n\\n'; printf 'error\\n' 1>&2"
+	for _, test := range []struct {
+		TrimOutput bool
+		Stdout     string
+		Stderr     string
+	}{
+		{TrimOutput: true, Stdout: "test", Stderr: "error"},
+		{TrimOutput: false, Stdout: "test\n\n", Stderr: "error\n"},
+	} {
+		// run command
+		cmd := NewCommand(command)
+		cmd.TrimOutput = test.TrimOutput
+		_ = cmd.Execute(context.Background())
+
+		// Verify expected stdout and stderr
+		assert.Equal(t, test.Stdout, cmd.Stdout)
+		assert.Equal(t, test.Stderr, cmd.Stderr)
+	}
+}
+
+// TestCommandCleanEnvironment tests the command with a clean environment.
+//
+// It sets a parent environment variable and verifies that it is passed
+// to the command only when the clean environment is disabled, while PATH
+// and the command environment are always passed.
+func TestCommandCleanEnvironment(t *testing.T) {
+	t.Setenv("YRG_PARENT_VAR", "dimple-unrest-ajar")
+	command := "echo ${YRG_PARENT_VAR}:${VAR1}:${PATH:+path}"
+	for _, test := range []struct {
+		CleanEnvironment bool
+		Stdout           string
+	}{
+		{CleanEnvironment: false, Stdout: "dimple-unrest-ajar:test:path"},
+		{CleanEnvironment: true, Stdout: ":test:path"},
+	} {
+		// run command
+		cmd := NewCommand(command)
+		cmd.Environment = map[string]string{"VAR1": "test"}
+		cmd.CleanEnvironment = test.CleanEnvironment
+		_ = cmd.Execute(context.Background())
+
+		// Verify expected stdout
+		assert.Equal(t, test.Stdout, cmd.Stdout)
+	}
+}
+
+// TestCommandEnvironmentFilters tests filtering the parent environment
+// with the allowlist and the denylist.
+//
+// It verifies that the denylist takes precedence over the allowlist and
+// that the command environment is never filtered.
+func TestCommandEnvironmentFilters(t *testing.T) {
+	t.Setenv("YRG_PUBLIC", "public")
+	t.Setenv("YRG_SECRET", "secret")
+	command := "echo ${YRG_PUBLIC:-}:${YRG_SECRET:-}:${VAR1}"
+	for _, test := range []struct {
+		EnvAllow []string
+		EnvDeny  []string
+		Stdout   string
+	}{
+		{Stdout: "public:secret:test"},
+		{EnvDeny: []string{"YRG_SECRET"}, Stdout: "public::test"},
+		{EnvAllow: []string{"YRG_PUBLIC"}, Stdout: "public::test"},
+		{EnvAllow: []string{"YRG_*"}, EnvDeny: []string{"*SECRET"},
+			Stdout: "public::test"},
+		{EnvAllow: []string{"YRG_PUBLIC"}, EnvDeny: []string{"YRG_PUBLIC"},
+			Stdout: "::test"},
+		{EnvDeny: []string{"[", "VAR1"}, Stdout: "public:secret:test"},
+	} {
+		// run command
+		cmd := NewCommand(command)
+		cmd.Environment = map[string]string{"VAR1": "test"}
+		cmd.EnvAllow = test.EnvAllow
+		cmd.EnvDeny = test.EnvDeny
+		_ = cmd.Execute(context.Background())
+
+		// Verify expected stdout
+		assert.Equal(t, test.Stdout, cmd.Stdout)
+	}
+}
+
+// TestCommandOutputFiles tests writing the command output to files.
+//
+// It verifies that the untrimmed output is written to the files in addition
+// to being captured, that combined output is written to the stdout file
+// and that a file which cannot be opened fails the command.
+func TestCommandOutputFiles(t *testing.T) {
+	dir := t.TempDir()
+	stdoutFile := filepath.Join(dir, "stdout.log")
+	stderrFile := filepath.Join(dir, "stderr.log")
+
+	// when: We run a command writing to the output files
+	cmd := NewCommand("echo out; echo err >&2")
+	cmd.StdoutFile = stdoutFile
+	cmd.StderrFile = stderrFile
+	err := cmd.Execute(context.Background())
+
+	// then: We check the captured output and the files
+	assert.Nil(t, err)
+	assert.Equal(t, "out", cmd.Stdout)
+	assert.Equal(t, "err", cmd.Stderr)
+	content, _ := os.ReadFile(stdoutFile)
+	assert.Equal(t, "out\n", string(content))
+	content, _ = os.ReadFile(stderrFile)
+	assert.Equal(t, "err\n", string(content))
+
+	// when: We run a command with combined output
+	cmd = NewCommand("echo out; echo err >&2")
+	cmd.CombineOutput = true
+	cmd.StdoutFile = stdoutFile
+	_ = cmd.Execute(context.Background())
+
+	// then: We check that the stdout file is truncated and contains both
+	content, _ = os.ReadFile(stdoutFile)
+	assert.Equal(t, "out\nerr\n", string(content))
+
+	// when: We run a command with an output file which cannot be opened
+	cmd = NewCommand("echo out")
+	cmd.StdoutFile = filepath.Join(dir, "missing", "stdout.log")
+	err = cmd.Execute(context.Background())
+
+	// then: We check that the command failed
+	assert.NotNil(t, err)
+	assert.Equal(t, -1, cmd.Rc)
+	assert.Equal(t, "", cmd.Stdout)
+}
+
+// TestCommandCombineOutputFile tests the combined output of the command
+// written also to the stderr file and streamed.
+//
+// It executes a command writing to stdout and stderr concurrently and
+// verifies that no output is lost, run with -race to detect data races.
+func TestCommandCombineOutputFile(t *testing.T) {
+	stderrFile := filepath.Join(t.TempDir(), "stderr.log")
+	_ = LogInit(LogConfig{File: "testing_buffer", Level: "debug"})
+
+	// when: We run a command writing to stdout and stderr concurrently
+	cmd := NewCommand("for i in $(seq 500); do echo out; done & " +
+		"for i in $(seq 500); do echo err >&2; done; wait")
+	cmd.CombineOutput = true
+	cmd.StderrFile = stderrFile
+	cmd.Stream = true
+	err := cmd.Execute(context.Background())
+
+	// then: We check that the whole output was captured
+	assert.Nil(t, err)
+	assert.Equal(t, 500, strings.Count(cmd.Stdout, "out"))
+	assert.Equal(t, 500, strings.Count(cmd.Stdout, "err"))
+	content, _ := os.ReadFile(stderrFile)
+	assert.Equal(t, strings.Repeat("err\n", 500), string(content))
+}
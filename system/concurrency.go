@@ -0,0 +1,45 @@
+package system
+
+import (
+	"context"
+	"sync"
+)
+
+// processSemaphore limits the number of simultaneously running commands.
+// The number of commands is not limited when nil.
+var processSemaphore chan struct{}
+
+// processSemaphoreMutex guards replacing the process semaphore.
+var processSemaphoreMutex sync.RWMutex
+
+// SetMaxConcurrency limits the number of simultaneously running commands
+// to n. Commands exceeding the limit wait until a running command
+// finishes. Zero or a negative n removes the limit, which is the default.
+func SetMaxConcurrency(n int) {
+	processSemaphoreMutex.Lock()
+	defer processSemaphoreMutex.Unlock()
+	if n <= 0 {
+		processSemaphore = nil
+		return
+	}
+	processSemaphore = make(chan struct{}, n)
+}
+
+// acquireProcess waits for a free slot to run a command. It returns
+// a function releasing the slot, or an error if the context is done
+// before a slot is free.
+func acquireProcess(ctx context.Context) (func(), error) {
+	processSemaphoreMutex.RLock()
+	semaphore := processSemaphore
+	processSemaphoreMutex.RUnlock()
+
+	if semaphore == nil {
+		return func() {}, nil
+	}
+	select {
+	case semaphore <- struct{}{}:
+		return func() { <-semaphore }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,52 @@
+package system
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSetMaxConcurrency tests limiting the number of simultaneously
+// running commands.
+//
+// It runs commands concurrently with a limit of one command and verifies
+// that they did not overlap, then verifies that a command waiting for
+// a slot is aborted when its context is cancelled.
+func TestSetMaxConcurrency(t *testing.T) {
+	SetMaxConcurrency(1)
+	defer SetMaxConcurrency(0)
+
+	// when: We run three commands concurrently
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := NewCommand("sleep 0.2")
+			_ = cmd.Execute(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	// then: We check that the commands ran one after another
+	assert.GreaterOrEqual(t, time.Since(start), 600*time.Millisecond)
+
+	// when: We run a command while the only slot is taken
+	release, err := acquireProcess(context.Background())
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(),
+		50*time.Millisecond)
+	defer cancel()
+	cmd := NewCommand("echo waiting")
+	err = cmd.Execute(ctx)
+	release()
+
+	// then: We check that the command was not executed
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, -1, cmd.Rc)
+	assert.Equal(t, "", cmd.Stdout)
+}
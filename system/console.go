@@ -0,0 +1,65 @@
+package system
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// levelColors maps the log levels to the ANSI colors of the console format.
+var levelColors = map[string]string{
+	"TRACE": "\x1b[90m",
+	"DEBUG": "\x1b[90m",
+	"INFO":  "\x1b[32m",
+	"WARN":  "\x1b[33m",
+	"ERROR": "\x1b[31m",
+}
+
+// colorReset is the ANSI code resetting the color.
+const colorReset = "\x1b[0m"
+
+// mockIsTerminal allows mocking the terminal detection in tests.
+var mockIsTerminal = isTerminal
+
+// isTerminal reports whether the output is a terminal and colors are not
+// disabled by the NO_COLOR environment variable.
+func isTerminal(output io.Writer) bool {
+	f, ok := output.(*os.File)
+	return ok && os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(f.Fd()))
+}
+
+// colorWriter colors the level of text log entries written to the output.
+type colorWriter struct {
+	output io.Writer
+}
+
+// Write writes the log entry to the output with the level=VALUE pair
+// colored by the level. Entries without a known level are written as is.
+func (w colorWriter) Write(p []byte) (int, error) {
+	start := bytes.Index(p, []byte("level="))
+	if start < 0 {
+		return w.output.Write(p)
+	}
+	end := bytes.IndexByte(p[start:], ' ')
+	if end < 0 {
+		return w.output.Write(p)
+	}
+	end += start
+	color, ok := levelColors[string(p[start+len("level="):end])]
+	if !ok {
+		return w.output.Write(p)
+	}
+
+	var entry bytes.Buffer
+	entry.Write(p[:start])
+	entry.WriteString(color)
+	entry.Write(p[start:end])
+	entry.WriteString(colorReset)
+	entry.Write(p[end:])
+	if _, err := w.output.Write(entry.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,106 @@
+package system
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestColorWriter verifies that the levels of log entries are colored
+// and entries without a known level are written as is.
+func TestColorWriter(t *testing.T) {
+	for input, expected := range map[string]string{
+		"time=now level=ERROR msg=failed\n": "time=now \x1b[31mlevel=ERROR" +
+			"\x1b[0m msg=failed\n",
+		"time=now level=INFO msg=ok\n": "time=now \x1b[32mlevel=INFO" +
+			"\x1b[0m msg=ok\n",
+		"time=now level=TRACE msg=ok\n": "time=now \x1b[90mlevel=TRACE" +
+			"\x1b[0m msg=ok\n",
+		"time=now level=FATAL msg=ok\n": "time=now level=FATAL msg=ok\n",
+		"msg=ok\n":                      "msg=ok\n",
+	} {
+		var output bytes.Buffer
+		n, err := colorWriter{output: &output}.Write([]byte(input))
+		assert.Nil(t, err)
+		assert.Equal(t, len(input), n)
+		assert.Equal(t, expected, output.String())
+	}
+}
+
+// TestLogFormats verifies that the log format takes precedence over
+// the JSON flag and that the console format and the color option color
+// levels of text entries on terminals only.
+func TestLogFormats(t *testing.T) {
+	defer func() {
+		mockIsTerminal = isTerminal
+	}()
+	for _, test := range []struct {
+		Config   LogConfig
+		Terminal bool
+		Expected string
+	}{
+		{Config: LogConfig{}, Expected: "level=WARN msg=entry"},
+		{Config: LogConfig{JSON: true}, Expected: `"level":"WARN"`},
+		{Config: LogConfig{Format: "logfmt", JSON: true},
+			Expected: "level=WARN msg=entry"},
+		{Config: LogConfig{Format: "json"}, Expected: `"level":"WARN"`},
+		{Config: LogConfig{Format: "console"},
+			Expected: " level=WARN msg=entry"},
+		{Config: LogConfig{Format: "console"}, Terminal: true,
+			Expected: "\x1b[33mlevel=WARN\x1b[0m msg=entry"},
+		{Config: LogConfig{Color: true}, Expected: " level=WARN msg=entry"},
+		{Config: LogConfig{Color: true}, Terminal: true,
+			Expected: "\x1b[33mlevel=WARN\x1b[0m msg=entry"},
+		{Config: LogConfig{Format: "json", Color: true}, Terminal: true,
+			Expected: `"level":"WARN"`},
+	} {
+		// given: We mock the terminal detection
+		mockIsTerminal = func(_ io.Writer) bool {
+			return test.Terminal
+		}
+
+		// when: We log a warning in the format
+		test.Config.File = "testing_buffer"
+		_ = LogInit(test.Config)
+		_ = Log("warn", "entry")
+
+		// then: We check the format of the log entry
+		assert.Contains(t, GetTestingStdout(), test.Expected)
+	}
+}
+
+// TestIsTerminal verifies that outputs which are not terminals are
+// detected.
+func TestIsTerminal(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "output")
+	assert.Nil(t, err)
+	defer file.Close()
+
+	assert.False(t, isTerminal(file))
+	assert.False(t, isTerminal(&bytes.Buffer{}))
+}
+
+// TestLogColorFile verifies that the color option does not color log
+// entries written to files.
+func TestLogColorFile(t *testing.T) {
+	mockIsTerminal = func(_ io.Writer) bool {
+		return true
+	}
+	defer func() {
+		mockIsTerminal = isTerminal
+	}()
+	file := filepath.Join(t.TempDir(), "runner.log")
+
+	// when: We log a warning with colors enabled
+	err := LogInit(LogConfig{File: file, Quiet: true, Color: true})
+	_ = Log("warn", "entry")
+
+	// then: We check that the log file is not colored
+	assert.Nil(t, err)
+	content, _ := os.ReadFile(file)
+	assert.Contains(t, string(content), " level=WARN msg=entry")
+}
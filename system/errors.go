@@ -1,37 +1,134 @@
 package system
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"runtime"
+	"sync"
 )
 
-var returnCodes = map[string]int{
-	"OK":              0,
-	"Unknown":         1,
-	"IOError":         64,
-	"ParseError":      65,
-	"ValidationError": 66,
-	"OSError":         67,
+// ErrorCode represents an exit status code mapped to an error name.
+type ErrorCode int
+
+// Built-in exit status codes.
+const (
+	CodeOK              ErrorCode = 0
+	CodeUnknown         ErrorCode = 1
+	CodeIOError         ErrorCode = 64
+	CodeParseError      ErrorCode = 65
+	CodeValidationError ErrorCode = 66
+	CodeOSError         ErrorCode = 67
+)
+
+var returnCodes = map[string]ErrorCode{
+	"OK":              CodeOK,
+	"Unknown":         CodeUnknown,
+	"IOError":         CodeIOError,
+	"ParseError":      CodeParseError,
+	"ValidationError": CodeValidationError,
+	"OSError":         CodeOSError,
 }
+var returnCodesMutex sync.RWMutex
 var MockOsExit = os.Exit
 
-// FatalError tries to write a log error and exist with the status code
-func FatalError(name string, msg string) {
+// RegisterErrorCode maps the error name to the exit status code. Existing
+// mappings, including the built-in ones, are replaced.
+func RegisterErrorCode(name string, code int) {
+	returnCodesMutex.Lock()
+	defer returnCodesMutex.Unlock()
+	returnCodes[name] = ErrorCode(code)
+}
+
+// ExitCode returns the exit status code mapped to the error name. Unknown
+// error names are mapped to the "Unknown" status code.
+func ExitCode(name string) int {
+	returnCodesMutex.RLock()
+	defer returnCodesMutex.RUnlock()
+	code, exists := returnCodes[name]
+	if !exists {
+		return int(CodeUnknown)
+	}
+	return int(code)
+}
+
+// ErrorCodes returns a copy of the mapping of error names to exit
+// status codes.
+func ErrorCodes() map[string]ErrorCode {
+	returnCodesMutex.RLock()
+	defer returnCodesMutex.RUnlock()
+	codes := make(map[string]ErrorCode, len(returnCodes))
+	for name, code := range returnCodes {
+		codes[name] = code
+	}
+	return codes
+}
+
+// Error represents an application error. The error name is mapped
+// to the exit status code.
+type Error struct {
+	Name string // error name, e.g. "IOError"
+	Err  error  // underlying error
+	file string // file where the error was created
+	line int    // line where the error was created
+	fn   string // function where the error was created
+}
+
+// NewError creates a new Error with the specified name wrapping
+// the underlying error. It saves the caller location for logging.
+func NewError(name string, err error) *Error {
 	// Get runtime info
 	pc, filename, line, _ := runtime.Caller(1)
 	fn := runtime.FuncForPC(pc).Name()
 
-	// Save logs
-	Log("error", fmt.Sprintf("FATAL ERROR: %s %s", name, msg), "file",
-		filename, "line", line, "fn", fn)
+	return &Error{Name: name, Err: err, file: filename, line: line, fn: fn}
+}
 
-	// Get return code number
-	code, exists := returnCodes[name]
-	if !exists {
-		code = 1
+// WrapError creates a new Error wrapping the error and named after its
+// type, e.g. "IOError" for *app.IOError, so the exit status code is
+// derived from the type. It saves the caller location for logging.
+func WrapError(err error) *Error {
+	// Get runtime info
+	pc, filename, line, _ := runtime.Caller(1)
+	fn := runtime.FuncForPC(pc).Name()
+
+	name := reflect.Indirect(reflect.ValueOf(err)).Type().Name()
+	return &Error{Name: name, Err: err, file: filename, line: line, fn: fn}
+}
+
+// Error returns the error name followed by the underlying error message.
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Name
+	}
+	return fmt.Sprintf("%s %s", e.Name, e.Err.Error())
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// FatalError tries to write a log error and exits with the status code
+// mapped to the error name. Errors other than Error exit with
+// the "Unknown" status code.
+func FatalError(err error) {
+	var appErr *Error
+	if !errors.As(err, &appErr) {
+		appErr = &Error{Name: "Unknown", Err: err}
+	}
+
+	// Save logs
+	message := fmt.Sprintf("FATAL ERROR: %s", appErr.Error())
+	params := []interface{}{"file", appErr.file, "line", appErr.line,
+		"fn", appErr.fn}
+	if currentLoggers() == nil {
+		// logging is not initialized yet
+		fmt.Fprintln(os.Stderr, message) // nolint:revive
 	}
+	Log("error", message, params...)
 
 	// Exit
-	MockOsExit(code)
+	MockOsExit(ExitCode(appErr.Name))
 }
@@ -1,6 +1,7 @@
 package system
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"testing"
@@ -76,7 +77,7 @@ func TestFatalError(t *testing.T) {
 
 	for _, test := range tests {
 		// Set log settings and clear buffers
-		LogInit(LogConfig{
+		_ = LogInit(LogConfig{
 			File:  "testing_buffer",
 			Level: "info",
 			Quiet: false,
@@ -84,7 +85,7 @@ func TestFatalError(t *testing.T) {
 		})
 
 		// Call the tested function
-		FatalError(test.name, test.error)
+		FatalError(NewError(test.name, errors.New(test.error)))
 
 		// Test return code
 		assert.Equal(t, test.expected, rc)
@@ -96,3 +97,100 @@ func TestFatalError(t *testing.T) {
 			GetTestingStderr())
 	}
 }
+
+// TestFatalErrorUnknown tests the FatalError function with an error
+// other than Error and with logging not initialized.
+//
+// It verifies that the application exits with the "Unknown" status code.
+func TestFatalErrorUnknown(t *testing.T) {
+	var rc int
+	MockOsExit = func(code int) {
+		rc = code
+	}
+	defer func() {
+		MockOsExit = os.Exit
+	}()
+
+	// Set log settings and clear buffers
+	_ = LogInit(LogConfig{File: "testing_buffer", Level: "info"})
+
+	// Call the tested function
+	FatalError(errors.New("dreamless-outsell-stingy"))
+
+	// Test return code and logs
+	assert.Equal(t, 1, rc)
+	assert.Regexp(t, " level=ERROR msg=\"FATAL ERROR: Unknown "+
+		"dreamless-outsell-stingy\" ", GetTestingStderr())
+
+	// Call the tested function with logging not initialized
+	loggers = nil
+	rc = 0
+	FatalError(errors.New("dreamless-outsell-stingy"))
+	assert.Equal(t, 1, rc)
+}
+
+// TestError tests the Error type.
+//
+// It verifies the error message, the unwrapped error and the caller
+// location saved by NewError.
+func TestError(t *testing.T) {
+	cause := errors.New("cause")
+	err := NewError("IOError", cause)
+
+	assert.Equal(t, "IOError cause", err.Error())
+	assert.Equal(t, "IOError", NewError("IOError", nil).Error())
+	assert.ErrorIs(t, err, cause)
+	assert.Contains(t, err.file, "errors_test.go")
+	assert.Contains(t, err.fn, "TestError")
+}
+
+// ParseError is an error type named like a built-in error name.
+type ParseError struct{ cause error }
+
+// Error returns the message of the cause.
+func (e *ParseError) Error() string { return e.cause.Error() }
+
+// TestWrapError tests creating an Error named after the type of the error.
+//
+// It verifies the derived name, the exit status code and the caller
+// location saved by WrapError.
+func TestWrapError(t *testing.T) {
+	typed := &ParseError{cause: errors.New("cause")}
+	err := WrapError(typed)
+
+	assert.Equal(t, "ParseError", err.Name)
+	assert.Equal(t, int(CodeParseError), ExitCode(err.Name))
+	assert.Equal(t, "ParseError cause", err.Error())
+	var target *ParseError
+	assert.ErrorAs(t, err, &target)
+	assert.Contains(t, err.file, "errors_test.go")
+	assert.Contains(t, err.fn, "TestWrapError")
+}
+
+// TestRegisterErrorCode tests registering custom exit status codes.
+//
+// It registers a new error name and verifies that it is mapped to the exit
+// status code, while unknown names are mapped to the "Unknown" code and
+// the built-in mappings are kept.
+func TestRegisterErrorCode(t *testing.T) {
+	const codeCustomError = 70
+	defer func() {
+		returnCodesMutex.Lock()
+		delete(returnCodes, "CustomError")
+		returnCodesMutex.Unlock()
+	}()
+
+	RegisterErrorCode("CustomError", codeCustomError)
+
+	assert.Equal(t, codeCustomError, ExitCode("CustomError"))
+	assert.Equal(t, int(CodeUnknown), ExitCode("NotRegisteredError"))
+	assert.Equal(t, int(CodeIOError), ExitCode("IOError"))
+
+	codes := ErrorCodes()
+	assert.Equal(t, ErrorCode(codeCustomError), codes["CustomError"])
+	assert.Equal(t, CodeOK, codes["OK"])
+
+	// modifying the copy does not change the mapping
+	codes["OK"] = CodeUnknown
+	assert.Equal(t, int(CodeOK), ExitCode("OK"))
+}
@@ -1,39 +1,140 @@
 package system
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/exp/slog"
 )
 
 // LogConfig represents the configuration options for logging.
 type LogConfig struct {
-	// The file path where log entries will be written.
-	File string `file,validate:"filepath"`
+	// The file path where log entries will be written, or "testing_buffer"
+	// to write them to the testing buffers.
+	File string `validate:"omitempty,filepath"`
 	// The minimal log level to be logged.
-	Level string `minimal_level,validate:"oneof=debug info warn error"`
+	Level string `validate:"omitempty,oneof=trace debug info warn error"`
 	// Whether to suppress console output of log entries.
 	Quiet bool
-	// Whether to format log entries in JSON format.
+	// Whether to still write errors to stderr in quiet mode.
+	QuietExceptErrors bool `yaml:"quiet_except_errors"`
+	// Whether to format log entries in JSON format, an alias for the json
+	// format.
 	JSON bool
+	// The format of log entries: text, json, logfmt or console. It takes
+	// precedence over JSON and defaults to text.
+	Format string `validate:"omitempty,oneof=text json logfmt console"`
+	// Whether to color the levels of text log entries written to
+	// the console, if it is a terminal.
+	Color bool
+	// Additional files where log entries will be written, each with its
+	// own minimal log level.
+	Files []LogFile `validate:"dive"`
+	// The octal permission of created log files, e.g. "0640", defaults
+	// to DefaultLogFileMode.
+	FileMode string `yaml:"file_mode" validate:"omitempty,filemode"`
+	// The octal permission of created parent directories of log files,
+	// e.g. "0750", defaults to DefaultLogDirMode.
+	DirMode string `yaml:"dir_mode" validate:"omitempty,dirmode"`
+	// Whether to truncate log files when they are opened instead of
+	// appending to them.
+	Truncate bool
+}
+
+// DefaultLogFileMode is the permission of created log files.
+const DefaultLogFileMode fs.FileMode = 0600
+
+// DefaultLogDirMode is the permission of created log directories.
+const DefaultLogDirMode fs.FileMode = 0700
+
+// ParseFileMode parses the octal permission of log files, e.g. "0640"
+// or "0o640". An empty mode returns DefaultLogFileMode. It returns
+// an error unless the mode is a permission granting the owner read and
+// write access.
+func ParseFileMode(mode string) (fs.FileMode, error) {
+	return parseMode(mode, DefaultLogFileMode)
+}
+
+// ParseDirMode parses the octal permission of log directories, e.g.
+// "0750". An empty mode returns DefaultLogDirMode. It returns an error
+// unless the mode is a permission granting the owner full access.
+func ParseDirMode(mode string) (fs.FileMode, error) {
+	return parseMode(mode, DefaultLogDirMode)
+}
+
+// parseMode parses the octal permission, which must grant at least
+// the owner permissions of the default mode. An empty mode returns
+// the default mode.
+func parseMode(mode string, defaultMode fs.FileMode) (fs.FileMode, error) {
+	if mode == "" {
+		return defaultMode, nil
+	}
+	value, err := strconv.ParseUint(strings.TrimPrefix(mode, "0o"), 8, 32)
+	if err != nil || fs.FileMode(value)&^fs.ModePerm != 0 {
+		return 0, fmt.Errorf("invalid mode %q", mode)
+	}
+	if fs.FileMode(value)&defaultMode != defaultMode {
+		return 0, fmt.Errorf("mode %q denies owner access", mode)
+	}
+	return fs.FileMode(value), nil
+}
+
+// LogFile represents an additional log file with its minimal log level.
+type LogFile struct {
+	// The file path where log entries will be written.
+	Path string `validate:"required,filepath"`
+	// The minimal log level to be logged, defaults to the LogConfig level.
+	Level string `validate:"omitempty,oneof=trace debug info warn error"`
 }
 
 var loggers map[string]*slog.Logger
 
+// loggersMutex guards loggers, fileTargets, customLoggers and
+// customTargets. The maps and slices are replaced, never modified once
+// set, except customLoggers, which is accessed only with the lock held.
+var loggersMutex sync.RWMutex
+
+// fileTargets holds the names of the file loggers.
+var fileTargets []string
+
+// customLoggers holds the loggers registered by SetLogger by their names.
+var customLoggers = map[string]*slog.Logger{}
+
+// customTargets holds the names of the custom loggers in the loggers map.
+var customTargets []string
+
+// ConsoleToStderr makes LogInit write the console log entries of all
+// levels to stderr, e.g. when stdout is used for other output.
+var ConsoleToStderr bool
+
+// customTargetPrefix prefixes the names of custom loggers in the loggers
+// map, so they do not collide with the built-in loggers.
+const customTargetPrefix = "custom:"
+
 // LogInit initializes the logging system based on the provided configuration.
-// It sets up loggers for writing to stdout/stderr or file, and sets the minimum
-// logging level. If the configuration specifies "testing_buffer" as the file,
-// it redirects logging output to a testing buffer.The loggers are stored in
-// the loggers map for later use.
-func LogInit(config LogConfig) {
+// It sets up loggers for writing to stdout/stderr or files, creating
+// the parent directories of the files if needed, and sets
+// the minimum logging level. Each additional file can have its own minimum
+// level. In quiet mode console output is suppressed, except errors written
+// to stderr if requested. If the configuration specifies "testing_buffer"
+// as the file, it redirects logging output to a testing buffer.
+// The loggers are stored in the loggers map for later use. It returns
+// a ValidationError if a mode is invalid and an IOError if a log file
+// cannot be opened.
+func LogInit(config LogConfig) error {
 	// stdout/stderr
 	var stdout io.Writer = os.Stdout
 	var stderr io.Writer = os.Stderr
-	// log file permission
-	const logFilePermission fs.FileMode = 0600
 
 	// buffer for testing
 	if config.File == "testing_buffer" {
@@ -42,77 +143,355 @@ func LogInit(config LogConfig) {
 		stdout = &testingStdout
 		stderr = &testingStderr
 	}
-
-	// set minimum logging level
-	var minimumLevel = new(slog.LevelVar)
-	switch config.Level {
-	default:
-		minimumLevel.Set(slog.LevelDebug)
-	case "info":
-		minimumLevel.Set(slog.LevelInfo)
-	case "warn":
-		minimumLevel.Set(slog.LevelWarn)
-	case "error":
-		minimumLevel.Set(slog.LevelError)
+	if ConsoleToStderr {
+		stdout = stderr
 	}
 
 	// default options
-	options := &slog.HandlerOptions{Level: minimumLevel}
+	options := handlerOptions(config.Level)
 
-	// We will collect loggers in the temporary variable.
+	// We will collect loggers in the temporary variables.
 	_loggers := map[string]*slog.Logger{}
+	_fileTargets := []string{}
 
-	// Initialize file logger if the file path is specified and
-	// is not "testing_buffer".
-	if config.File != "" && config.File != "testing_buffer" {
-		f, err := os.OpenFile(config.File, os.O_RDWR|os.O_CREATE|os.O_APPEND,
-			logFilePermission)
+	// Initialize file loggers.
+	for name, file := range logFiles(config) {
+		logger, err := fileLogHandler(file, options, config)
 		if err != nil {
-			FatalError("IOError", err.Error())
+			return err
 		}
-		_loggers["file"] = logHandler(f, options, config)
+		_loggers[name] = logger
+		_fileTargets = append(_fileTargets, name)
 	}
+	slices.Sort(_fileTargets)
 
 	// Initialize stdout logger if Quiet flag is not set.
 	if !config.Quiet {
 		_loggers["stdout"] = logHandler(stdout, options, config)
+	}
+	// Initialize stderr logger unless errors are suppressed by Quiet flag.
+	if !config.Quiet || config.QuietExceptErrors {
 		_loggers["stderr"] = logHandler(stderr, options, config)
 	}
 
-	// Set the loggers variable to the collected loggers.
-	loggers = _loggers
+	// Set the loggers variables to the collected loggers.
+	loggersMutex.Lock()
+	defer loggersMutex.Unlock()
+	loggers, customTargets = withCustomLoggers(_loggers)
+	fileTargets = _fileTargets
+	return nil
+}
+
+// Bind adds the parameters to every log message saved by Log, e.g. an ID
+// correlating the messages of a run, until logging is initialized again
+// by LogInit. Custom loggers registered later by SetLogger do not get
+// the parameters.
+func Bind(params ...interface{}) {
+	loggersMutex.Lock()
+	defer loggersMutex.Unlock()
+	loggers = (&Logger{loggers: loggers}).With(params...).loggers
+}
+
+// logFiles returns the log files of the configuration by the names of their
+// loggers: the file path, unless it is "testing_buffer", and the additional
+// files. Date tokens in the paths are expanded with the current time.
+func logFiles(config LogConfig) map[string]LogFile {
+	files := map[string]LogFile{}
+	now := mockNow()
+	if config.File != "" && config.File != "testing_buffer" {
+		files["file"] = LogFile{Path: expandDateTokens(config.File, now)}
+	}
+	for _, file := range config.Files {
+		name := "file:" + file.Path
+		file.Path = expandDateTokens(file.Path, now)
+		files[name] = file
+	}
+	return files
+}
+
+// mockNow allows mocking the current time in tests.
+var mockNow = time.Now
+
+// expandDateTokens replaces the strftime-style tokens %Y (year), %m
+// (month), %d (day) and %H (hour) in the path with the time, e.g. for
+// daily log files, and %% with a percent sign.
+func expandDateTokens(path string, t time.Time) string {
+	return strings.NewReplacer(
+		"%%", "%",
+		"%Y", t.Format("2006"),
+		"%m", t.Format("01"),
+		"%d", t.Format("02"),
+		"%H", t.Format("15"),
+	).Replace(path)
+}
+
+// SetLogger registers a custom logger, e.g. created with slog.New for
+// an OpenTelemetry handler, which receives log entries of all levels
+// in addition to the built-in log targets. The handler of the logger
+// filters the entries by level. The logger replaces the custom logger
+// registered with the same name, a nil logger removes it. Custom loggers
+// are kept when logging is initialized again by LogInit.
+func SetLogger(name string, logger *slog.Logger) {
+	loggersMutex.Lock()
+	defer loggersMutex.Unlock()
+	if logger == nil {
+		delete(customLoggers, name)
+	} else {
+		customLoggers[name] = logger
+	}
+
+	// keep the built-in loggers
+	_loggers := map[string]*slog.Logger{}
+	for target, l := range loggers {
+		if !strings.HasPrefix(target, customTargetPrefix) {
+			_loggers[target] = l
+		}
+	}
+	loggers, customTargets = withCustomLoggers(_loggers)
+}
+
+// withCustomLoggers adds the custom loggers to the loggers map and returns
+// the map with the sorted names of the custom log targets.
+func withCustomLoggers(
+	_loggers map[string]*slog.Logger) (map[string]*slog.Logger, []string) {
+	targets := []string{}
+	for name, logger := range customLoggers {
+		_loggers[customTargetPrefix+name] = logger
+		targets = append(targets, customTargetPrefix+name)
+	}
+	slices.Sort(targets)
+	return _loggers, targets
+}
+
+// levelAliases maps alternative level names to the supported ones.
+var levelAliases = map[string]string{
+	"warning":  "warn",
+	"err":      "error",
+	"fatal":    "error",
+	"critical": "error",
+}
+
+// NormalizeLevel returns the supported name of the log level, e.g. "warn"
+// for "WARNING". Names are case insensitive and aliases are mapped to
+// the supported names. Unknown names are returned in lowercase.
+func NormalizeLevel(level string) string {
+	name := strings.ToLower(strings.TrimSpace(level))
+	if alias, ok := levelAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// LevelTrace is the level of trace log messages, e.g. the environment
+// of executed commands, logged only if the minimal level is trace.
+const LevelTrace = slog.Level(-8)
+
+// handlerOptions returns the options of log handlers with the minimal
+// logging level for the level name, naming the trace level TRACE.
+func handlerOptions(level string) *slog.HandlerOptions {
+	return &slog.HandlerOptions{
+		Level:       logLevel(level),
+		ReplaceAttr: replaceTraceLevel,
+	}
+}
+
+// replaceTraceLevel names the trace level TRACE instead of DEBUG-4.
+func replaceTraceLevel(_ []string, attr slog.Attr) slog.Attr {
+	if attr.Key == slog.LevelKey && attr.Value.Any() == LevelTrace {
+		attr.Value = slog.StringValue("TRACE")
+	}
+	return attr
+}
+
+// logLevel returns the minimal logging level for the level name.
+// Unknown level names enable all levels except trace.
+func logLevel(level string) *slog.LevelVar {
+	var minimumLevel = new(slog.LevelVar)
+	switch NormalizeLevel(level) {
+	default:
+		minimumLevel.Set(slog.LevelDebug)
+	case "trace":
+		minimumLevel.Set(LevelTrace)
+	case "info":
+		minimumLevel.Set(slog.LevelInfo)
+	case "warn":
+		minimumLevel.Set(slog.LevelWarn)
+	case "error":
+		minimumLevel.Set(slog.LevelError)
+	}
+	return minimumLevel
+}
+
+// fileLogHandler creates the parent directories of the log file, opens
+// the file for appending, or truncates it if requested, and creates
+// a logger writing to it. The file level overrides the level
+// of the options. It returns a ValidationError if a mode is invalid and
+// an IOError if the file cannot be opened.
+func fileLogHandler(file LogFile, options *slog.HandlerOptions,
+	config LogConfig) (*slog.Logger, error) {
+	// log file permission
+	logFilePermission, err := ParseFileMode(config.FileMode)
+	if err != nil {
+		return nil, NewError("ValidationError", err)
+	}
+	// log directory permission
+	logDirPermission, err := ParseDirMode(config.DirMode)
+	if err != nil {
+		return nil, NewError("ValidationError", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(file.Path), logDirPermission)
+	if err != nil {
+		return nil, NewError("IOError", err)
+	}
+	flags := os.O_RDWR | os.O_CREATE | os.O_APPEND
+	if config.Truncate {
+		flags = os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(file.Path, flags, logFilePermission)
+	if err != nil {
+		return nil, NewError("IOError", err)
+	}
+	if file.Level != "" {
+		options = handlerOptions(file.Level)
+	}
+	// colors are enabled for the console only
+	fileConfig := config
+	fileConfig.Color = false
+	return logHandler(f, options, fileConfig), nil
+}
+
+// logFormat returns the format of log entries, using JSON as an alias
+// for the json format.
+func (c LogConfig) logFormat() string {
+	switch {
+	case c.Format != "":
+		return c.Format
+	case c.JSON:
+		return "json"
+	default:
+		return "text"
+	}
 }
 
 // logHandler creates a logger with the specified output, options,
-// and JSON format flag.
+// and format. The text and logfmt formats write logfmt key=value pairs,
+// the console format, or any of them if color is enabled, colors
+// the levels as well if the output is a terminal.
 func logHandler(output io.Writer, options *slog.HandlerOptions,
 	config LogConfig) *slog.Logger {
-	switch config.JSON {
-	case true:
+	format := config.logFormat()
+	if format == "json" {
 		return slog.New(slog.NewJSONHandler(output, options))
-	default:
-		return slog.New(slog.NewTextHandler(output, options))
 	}
+	if (format == "console" || config.Color) && mockIsTerminal(output) {
+		return slog.New(slog.NewTextHandler(colorWriter{output: output},
+			options))
+	}
+	return slog.New(slog.NewTextHandler(output, options))
 }
 
+// ErrIncorrectLogLevel is returned by Log for an unknown log level.
+var ErrIncorrectLogLevel = errors.New("incorrect log level")
+
+// incorrectLevelOnce ensures the warning about an unknown log level
+// is logged only once.
+var incorrectLevelOnce sync.Once
+
 // Log saves a log message with the specified level and parameters
-// to the configured log targets.
-func Log(level string, message string, params ...interface{}) {
+// to the configured log targets. Messages with an unknown level are
+// saved as warnings and ErrIncorrectLogLevel is returned.
+func Log(level string, message string, params ...interface{}) error {
+	return logTo(currentLoggers(), level, message, params...)
+}
+
+// currentLoggers returns the loggers of the log targets set by LogInit,
+// Bind and SetLogger.
+func currentLoggers() map[string]*slog.Logger {
+	loggersMutex.RLock()
+	defer loggersMutex.RUnlock()
+	return loggers
+}
+
+// logTo saves a log message to the loggers of the log targets enabled
+// for the level.
+func logTo(targets map[string]*slog.Logger, level string, message string,
+	params ...interface{}) error {
+	var err error
+	if !slices.Contains([]string{"trace", "debug", "info", "warn", "error"},
+		level) {
+		err = fmt.Errorf("%w: %s", ErrIncorrectLogLevel, level)
+		incorrectLevelOnce.Do(func() {
+			_ = Log("warn", "incorrect log level, logging as warn",
+				"level", level)
+		})
+	}
+
 	for _, handler := range logTargets(level) {
-		switch level {
-		case "debug":
-			loggers[handler].Debug(message, params...)
-		case "info":
-			loggers[handler].Info(message, params...)
-		case "warn":
-			loggers[handler].Warn(message, params...)
-		case "error":
-			loggers[handler].Error(message, params...)
-		default:
-			loggers[handler].Warn(message, params...)
-			panic(fmt.Sprintf("last log has incorrect level: %s", level))
+		if logger, ok := targets[handler]; ok {
+			logEntry(logger, level, message, params...)
 		}
 	}
+	return err
+}
+
+// logEntry saves a log message with the level using the logger.
+// Unknown levels are saved as warnings.
+func logEntry(logger *slog.Logger, level string, message string,
+	params ...interface{}) {
+	switch level {
+	case "trace":
+		logger.Log(context.Background(), LevelTrace, message, params...)
+	case "debug":
+		logger.Debug(message, params...)
+	case "info":
+		logger.Info(message, params...)
+	case "error":
+		logger.Error(message, params...)
+	default:
+		logger.Warn(message, params...)
+	}
+}
+
+// traceEnabled reports whether any log target logs trace messages.
+func traceEnabled() bool {
+	for _, logger := range currentLoggers() {
+		if logger.Enabled(context.Background(), LevelTrace) {
+			return true
+		}
+	}
+	return false
+}
+
+// Logger saves log messages with a set of bound parameters, e.g. details
+// of an executed command, to the configured log targets.
+type Logger struct {
+	loggers map[string]*slog.Logger
+}
+
+// With creates a Logger which adds the parameters to every log message.
+// The Logger uses the log targets configured by LogInit at the time
+// of the call.
+func With(params ...interface{}) *Logger {
+	return (&Logger{loggers: currentLoggers()}).With(params...)
+}
+
+// With creates a Logger which adds the parameters to every log message
+// in addition to the parameters already bound to the Logger.
+func (l *Logger) With(params ...interface{}) *Logger {
+	bound := map[string]*slog.Logger{}
+	for handler, logger := range l.loggers {
+		bound[handler] = logger.With(params...)
+	}
+	return &Logger{loggers: bound}
+}
+
+// Log saves a log message with the specified level, bound parameters and
+// parameters. Messages with an unknown level are saved as warnings and
+// ErrIncorrectLogLevel is returned.
+func (l *Logger) Log(level string, message string,
+	params ...interface{}) error {
+	return logTo(l.loggers, level, message, params...)
 }
 
 // logTargets returns a list of log targets based on the specified level.
@@ -127,7 +506,10 @@ func logTargets(level string) []string {
 		output = "stdout"
 	}
 
-	for _, handler := range []string{output, "file"} {
+	loggersMutex.RLock()
+	defer loggersMutex.RUnlock()
+	handlers := append([]string{output}, fileTargets...)
+	for _, handler := range append(handlers, customTargets...) {
 		_, handlerEnabled := loggers[handler]
 		if handlerEnabled {
 			targets = append(targets, handler)
@@ -169,8 +551,19 @@ func (b *LogBuilder) Set(params ...interface{}) *LogBuilder {
 	return b
 }
 
+// missingLogValue is the value added to a log parameter key without
+// a value.
+const missingLogValue = "(MISSING)"
+
 // Save builds the log parameters and invokes the Log function
-// to save the log message.
+// to save the log message. If the parameters do not form key-value pairs,
+// a warning is logged and the last key gets a placeholder value.
 func (b *LogBuilder) Save() {
-	Log(b.level, b.message, b.params...)
+	params := b.params
+	if len(params)%2 != 0 {
+		_ = Log("warn", "odd number of log parameters", "message", b.message,
+			"key", fmt.Sprint(params[len(params)-1]))
+		params = append(params, missingLogValue)
+	}
+	_ = Log(b.level, b.message, params...)
 }
@@ -1,13 +1,20 @@
 package system
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
 )
 
 const stderrLevel string = "error"
@@ -83,7 +90,7 @@ func TestLogTargets(t *testing.T) {
 		// logging init
 		config := LogConfig{File: test.file, Quiet: test.quiet, JSON: false,
 			Level: test.level}
-		LogInit(config)
+		_ = LogInit(config)
 		// get targets
 		targets := logTargets(test.level)
 		// check number of targets
@@ -108,7 +115,7 @@ func TestLogTargets(t *testing.T) {
 func TestLogTextHandler(t *testing.T) {
 	for _, level := range []string{"debug", "info", "warn", "error"} {
 		// log buffering, text format
-		LogInit(LogConfig{File: "testing_buffer", Quiet: false, JSON: false})
+		_ = LogInit(LogConfig{File: "testing_buffer", Quiet: false, JSON: false})
 
 		// log
 		Log(level, "logging test", "field1", "preface-flinch-suspense")
@@ -146,7 +153,7 @@ func TestLogJSONHandler(t *testing.T) {
 		}
 
 		// log buffering, JSON format
-		LogInit(LogConfig{File: "testing_buffer", Quiet: false, JSON: true})
+		_ = LogInit(LogConfig{File: "testing_buffer", Quiet: false, JSON: true})
 
 		// log
 		Log(level, "TestLogJSONHandler", "field1", "zen-snagged-travel")
@@ -236,7 +243,7 @@ func TestSet(t *testing.T) {
 func TestSave(t *testing.T) {
 	for _, level := range []string{"debug", "info", "warn", "error"} {
 		// log buffering, text format
-		LogInit(LogConfig{File: "testing_buffer", Quiet: false, JSON: false})
+		_ = LogInit(LogConfig{File: "testing_buffer", Quiet: false, JSON: false})
 
 		// Log
 		message := "audio-yen-suing"
@@ -264,23 +271,405 @@ func TestSave(t *testing.T) {
 	}
 }
 
-// TestLogIncorrectLevel verifies that Log function will panic when incorrect
-// log level is passed.
+// TestLogIncorrectLevel verifies that Log function saves the message as
+// a warning and returns an error when incorrect log level is passed.
+// The misuse is reported only once.
 func TestLogIncorrectLevel(t *testing.T) {
-	LogInit(LogConfig{File: "testing_buffer", Quiet: false, JSON: false})
-	assert.Panics(t, func() { Log("incorrect_level", "msg") })
+	incorrectLevelOnce = sync.Once{}
+	_ = LogInit(LogConfig{File: "testing_buffer", Quiet: false, JSON: false})
+
+	// when: We log twice with an incorrect level
+	err := Log("incorrect_level", "first", "field1", "zoom-amino-wobbly")
+	assert.ErrorIs(t, err, ErrIncorrectLogLevel)
+	NewLogBuilder("second").Level("incorrect_level").Save()
+
+	// then: We check that the messages were saved as warnings
+	got := testingStdout.String()
+	assert.Contains(t, got, "level=WARN msg=first field1=zoom-amino-wobbly")
+	assert.Contains(t, got, "level=WARN msg=second")
+	assert.Equal(t, 1, strings.Count(got, "msg=\"incorrect log level, "+
+		"logging as warn\" level=incorrect_level"))
+
+	// then: We check that a correct level returns no error
+	assert.Nil(t, Log("info", "third"))
 }
 
+// TestNormalizeLevel verifies that level names are case insensitive and
+// that aliases are mapped to the supported level names.
+func TestNormalizeLevel(t *testing.T) {
+	for input, expected := range map[string]string{
+		"DEBUG":    "debug",
+		" Info ":   "info",
+		"warning":  "warn",
+		"WARNING":  "warn",
+		"err":      "error",
+		"fatal":    "error",
+		"critical": "error",
+		"":         "",
+		"Verbose":  "verbose",
+	} {
+		assert.Equal(t, expected, NormalizeLevel(input), input)
+	}
+}
+
+// TestLogInitNormalizedLevel verifies that LogInit accepts uppercase and
+// alias level names.
+func TestLogInitNormalizedLevel(t *testing.T) {
+	// given: We initialize logging with an alias level name
+	_ = LogInit(LogConfig{File: "testing_buffer", Level: "WARNING"})
+
+	// when: We log an info and a warning message
+	_ = Log("info", "skipped")
+	_ = Log("warn", "saved")
+
+	// then: We check that only the warning was saved
+	got := testingStdout.String()
+	assert.NotContains(t, got, "msg=skipped")
+	assert.Contains(t, got, "level=WARN msg=saved")
+}
+
+// TestLogQuietExceptErrors verifies that errors are still written
+// to stderr in quiet mode if requested, while other levels are suppressed.
+func TestLogQuietExceptErrors(t *testing.T) {
+	// when: We log in quiet mode with errors enabled
+	_ = LogInit(LogConfig{File: "testing_buffer", Quiet: true,
+		QuietExceptErrors: true})
+	Log("info", "suppressed")
+	Log("error", "failed", "field1", "amber-quill-meadow")
+
+	// then: We check that only the error was written to stderr
+	assert.Empty(t, GetTestingStdout())
+	assert.Contains(t, GetTestingStderr(),
+		"level=ERROR msg=failed field1=amber-quill-meadow")
+	assert.Equal(t, []string{"stderr"}, logTargets("error"))
+	assert.Empty(t, logTargets("info"))
+
+	// when: We log in quiet mode without errors enabled
+	_ = LogInit(LogConfig{File: "testing_buffer", Quiet: true})
+	Log("error", "failed")
+
+	// then: We check that the error was suppressed
+	assert.Empty(t, GetTestingStderr())
+}
+
+// TestLogInitInvalidFilePath verifies that LogInit returns an IOError
+// when the log file cannot be opened, e.g. because its parent is a file.
 func TestLogInitInvalidFilePath(t *testing.T) {
-	var rc int
-	const codeIOError = 64
-	MockOsExit = func(code int) {
-		rc = code
+	parent := filepath.Join(t.TempDir(), "file")
+	assert.Nil(t, os.WriteFile(parent, nil, 0600))
+	err := LogInit(LogConfig{File: filepath.Join(parent, "runner.log"),
+		Quiet: true, JSON: false})
+
+	var appErr *Error
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, "IOError", appErr.Name)
+}
+
+// TestParseFileMode verifies that octal permissions granting the owner
+// the access of the default mode are accepted.
+func TestParseFileMode(t *testing.T) {
+	for input, expected := range map[string]fs.FileMode{
+		"":      DefaultLogFileMode,
+		"0640":  0640,
+		"660":   0660,
+		"0o600": 0600,
+	} {
+		mode, err := ParseFileMode(input)
+		assert.Nil(t, err, input)
+		assert.Equal(t, expected, mode, input)
+	}
+	for _, input := range []string{"rw-r-----", "0999", "01777", "0400"} {
+		_, err := ParseFileMode(input)
+		assert.NotNil(t, err, input)
 	}
+	mode, err := ParseDirMode("")
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultLogDirMode, mode)
+	_, err = ParseDirMode("0600")
+	assert.NotNil(t, err)
+}
+
+// TestLogInitFileMode verifies that log files are created with
+// the configured permission, and that LogInit returns a ValidationError
+// for an invalid permission.
+func TestLogInitFileMode(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "runner.log")
+
+	// when: We initialize logging with a group readable log file
+	err := LogInit(LogConfig{File: file, Quiet: true, FileMode: "0640"})
+
+	// then: We check the permission of the log file
+	assert.Nil(t, err)
+	info, err := os.Stat(file)
+	assert.Nil(t, err)
+	assert.Equal(t, fs.FileMode(0640), info.Mode().Perm())
+
+	// when: We initialize logging with an invalid permission
+	err = LogInit(LogConfig{File: file, Quiet: true, FileMode: "0999"})
+
+	// then: We check that a ValidationError is returned
+	var appErr *Error
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, "ValidationError", appErr.Name)
+}
+
+// TestLogInitParentDirectories verifies that LogInit creates the missing
+// parent directories of log files with the configured permission.
+func TestLogInitParentDirectories(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "var", "log")
+	file := filepath.Join(dir, "runner.log")
+
+	// when: We initialize logging with a log file in a missing directory
+	err := LogInit(LogConfig{File: file, Quiet: true, DirMode: "0750"})
+	Log("info", "entry")
+
+	// then: We check the directory permission and the log file
+	assert.Nil(t, err)
+	info, err := os.Stat(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, fs.FileMode(0750), info.Mode().Perm())
+	content, _ := os.ReadFile(file)
+	assert.Contains(t, string(content), "msg=entry")
+
+	// when: We initialize logging with an invalid directory permission
+	err = LogInit(LogConfig{File: file, Quiet: true, DirMode: "0600"})
+
+	// then: We check that a ValidationError is returned
+	var appErr *Error
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, "ValidationError", appErr.Name)
+}
+
+// TestLogInitTruncate verifies that log files are appended to by default
+// and truncated if requested.
+func TestLogInitTruncate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "runner.log")
+	for _, test := range []struct {
+		Message  string
+		Truncate bool
+		Expected string
+	}{
+		{Message: "first", Expected: "msg=first\n$"},
+		{Message: "second", Expected: "msg=first\n.*msg=second\n$"},
+		{Message: "third", Truncate: true, Expected: "^[^\n]*msg=third\n$"},
+	} {
+		// when: We initialize logging and log a message
+		err := LogInit(LogConfig{File: file, Quiet: true,
+			Truncate: test.Truncate})
+		assert.Nil(t, err)
+		Log("info", test.Message)
+
+		// then: We check the contents of the log file
+		content, _ := os.ReadFile(file)
+		assert.Regexp(t, test.Expected, string(content))
+	}
+}
+
+// TestSetLogger verifies that custom loggers receive log entries
+// in addition to the built-in log targets, are kept by LogInit and can be
+// removed.
+func TestSetLogger(t *testing.T) {
+	var custom bytes.Buffer
+	_ = LogInit(LogConfig{File: "testing_buffer"})
+
+	// when: We register a custom logger and log an error
+	SetLogger("otel", slog.New(slog.NewJSONHandler(&custom, nil)))
+	defer SetLogger("otel", nil)
+	Log("error", "first", "field1", "brisk-otter-lantern")
+
+	// then: We check that the entry was saved by both loggers
+	assert.Contains(t, custom.String(),
+		`"level":"ERROR","msg":"first","field1":"brisk-otter-lantern"`)
+	assert.Contains(t, GetTestingStderr(), "msg=first")
+	assert.Equal(t, []string{"stderr", "custom:otel"}, logTargets("error"))
+
+	// when: We initialize logging again and log with bound parameters
+	_ = LogInit(LogConfig{File: "testing_buffer", Quiet: true})
+	With("field2", "dusky-pine-harbor").Log("info", "second")
+
+	// then: We check that the custom logger was kept
+	assert.Contains(t, custom.String(),
+		`"msg":"second","field2":"dusky-pine-harbor"`)
+
+	// when: We remove the custom logger
+	SetLogger("otel", nil)
+	custom.Reset()
+	Log("info", "third")
+
+	// then: We check that the custom logger was removed
+	assert.Empty(t, custom.String())
+	assert.Empty(t, logTargets("info"))
+}
+
+// TestSetLoggerConcurrent verifies that custom loggers can be registered
+// and parameters bound while other goroutines log, run with -race to
+// detect data races.
+func TestSetLoggerConcurrent(t *testing.T) {
+	_ = LogInit(LogConfig{File: "testing_buffer", Quiet: true})
+	defer SetLogger("concurrent", nil)
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// when: We log while registering loggers and binding parameters
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				SetLogger("concurrent", discard)
+				Bind("worker", i)
+				With("field", "value").Log("info", "bound")
+				Log("info", "message")
+				SetLogger("concurrent", nil)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// then: We check that the custom logger was removed
+	assert.Empty(t, logTargets("info"))
+}
+
+// TestBind verifies that bound parameters are added to every log message
+// until logging is initialized again.
+func TestBind(t *testing.T) {
+	// when: We bind a parameter and log a message
+	_ = LogInit(LogConfig{File: "testing_buffer"})
+	Bind("run_id", "merry-falcon-ledge")
+	Log("info", "first", "field1", "value")
+
+	// then: We check that the parameter was added
+	assert.Contains(t, GetTestingStdout(),
+		"msg=first run_id=merry-falcon-ledge field1=value")
+
+	// when: We initialize logging again and log a message
+	_ = LogInit(LogConfig{File: "testing_buffer"})
+	Log("info", "second")
+
+	// then: We check that the parameter was removed
+	assert.NotContains(t, GetTestingStdout(), "run_id")
+}
+
+// TestLogMultipleFiles verifies that log entries are written to multiple
+// files, each filtered by its own minimum level.
+func TestLogMultipleFiles(t *testing.T) {
+	dir := t.TempDir()
+	audit := filepath.Join(dir, "audit.log")
+	debug := filepath.Join(dir, "debug.log")
+
+	// when: We log to an audit and a debug log file
+	err := LogInit(LogConfig{
+		Quiet: true,
+		Level: "debug",
+		Files: []LogFile{
+			{Path: audit, Level: "info"},
+			{Path: debug},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"file:" + audit, "file:" + debug},
+		logTargets("debug"))
+	Log("debug", "debug entry", "field1", "mantis-uplift-sprout")
+	Log("info", "info entry", "field1", "mantis-uplift-sprout")
+
+	// then: We check that entries were filtered by the file levels
+	auditContent, _ := os.ReadFile(audit)
+	debugContent, _ := os.ReadFile(debug)
+	assert.NotContains(t, string(auditContent), "debug entry")
+	assert.Contains(t, string(auditContent), "info entry")
+	assert.Contains(t, string(debugContent), "debug entry")
+	assert.Contains(t, string(debugContent), "info entry")
+
+	// then: We check that an invalid file path returns an IOError
+	err = LogInit(LogConfig{Files: []LogFile{{Path: audit + "/file"}}})
+	var appErr *Error
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, "IOError", appErr.Name)
+}
+
+// TestLogFileDateTokens verifies that date tokens in the log file paths
+// are expanded with the current time when logging is initialized, so
+// a new file is started when the date changes.
+func TestLogFileDateTokens(t *testing.T) {
+	dir := t.TempDir()
 	defer func() {
-		MockOsExit = os.Exit
+		mockNow = time.Now
 	}()
+	mockNow = func() time.Time {
+		return time.Date(2024, 3, 7, 9, 0, 0, 0, time.UTC)
+	}
+
+	// when: We log to files with date tokens in their paths
+	err := LogInit(LogConfig{
+		Quiet: true,
+		File:  filepath.Join(dir, "%Y/runner-%Y-%m-%d-%H.log"),
+		Files: []LogFile{{Path: filepath.Join(dir, "audit-%d-100%%.log")}},
+	})
+	assert.Nil(t, err)
+	Log("info", "first day")
+
+	// then: We check that the tokens were expanded
+	content, _ := os.ReadFile(
+		filepath.Join(dir, "2024/runner-2024-03-07-09.log"))
+	assert.Contains(t, string(content), "first day")
+	content, _ = os.ReadFile(filepath.Join(dir, "audit-07-100%.log"))
+	assert.Contains(t, string(content), "first day")
+
+	// when: We initialize logging again the next day
+	mockNow = func() time.Time {
+		return time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)
+	}
+	err = LogInit(LogConfig{
+		Quiet: true,
+		File:  filepath.Join(dir, "%Y/runner-%Y-%m-%d-%H.log"),
+	})
+	assert.Nil(t, err)
+	Log("info", "second day")
+
+	// then: We check that a new file was started
+	content, _ = os.ReadFile(
+		filepath.Join(dir, "2024/runner-2024-03-08-00.log"))
+	assert.Contains(t, string(content), "second day")
+	assert.NotContains(t, string(content), "first day")
+}
+
+// TestSaveOddParams verifies that Save handles an odd number of parameters
+// gracefully, adding a placeholder value and logging a warning instead
+// of a corrupted entry.
+func TestSaveOddParams(t *testing.T) {
+	_ = LogInit(LogConfig{File: "testing_buffer", Quiet: false, JSON: false})
+
+	// when: We save an entry with three parameters
+	NewLogBuilder("odd").Level("info").
+		Set("field1", "elope-twine-bagful", "field2").Save()
+
+	// then: We check that the last key got a placeholder value
+	got := testingStdout.String()
+	assert.NotContains(t, got, "!BADKEY")
+	assert.Contains(t, got, "level=WARN msg=\"odd number of log parameters\" "+
+		"message=odd key=field2")
+	assert.Contains(t, got, "level=INFO msg=odd field1=elope-twine-bagful "+
+		"field2=(MISSING)")
+}
 
-	LogInit(LogConfig{File: "/not/existing/file", Quiet: true, JSON: false})
-	assert.Equal(t, codeIOError, rc)
+// TestLoggerWith verifies that a Logger adds the bound parameters to every
+// log message and that binding more parameters does not change the parent
+// Logger.
+func TestLoggerWith(t *testing.T) {
+	_ = LogInit(LogConfig{File: "testing_buffer", Quiet: false, JSON: false})
+
+	// when: We log with bound parameters
+	logger := With("field1", "pelican-gusto-outer")
+	_ = logger.Log("info", "first", "field2", 1)
+	_ = logger.With("field3", true).Log("error", "second")
+	err := logger.Log("incorrect_level", "third")
+
+	// then: We check the log messages
+	assert.Contains(t, testingStdout.String(), "level=INFO msg=first "+
+		"field1=pelican-gusto-outer field2=1\n")
+	assert.Contains(t, testingStderr.String(), "level=ERROR msg=second "+
+		"field1=pelican-gusto-outer field3=true\n")
+	assert.Contains(t, testingStdout.String(), "level=WARN msg=third "+
+		"field1=pelican-gusto-outer\n")
+	assert.ErrorIs(t, err, ErrIncorrectLogLevel)
 }